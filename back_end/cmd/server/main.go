@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,6 +23,9 @@ import (
 	"movies-api/internal/auth"
 	"movies-api/internal/cache"
 	"movies-api/internal/graphql"
+	"movies-api/internal/mail"
+	"movies-api/internal/model"
+	"movies-api/internal/oidc"
 	"movies-api/internal/omdb"
 )
 
@@ -29,17 +41,95 @@ func main() {
 		log.Fatal("OMDB_API_KEY não definida no ambiente")
 	}
 
-	// Inicializa o cache com validade de 6 horas
-	cache := cache.NewCache(6 * time.Hour)
+	// Carrega as chaves de assinatura dos tokens JWT
+	keySet, err := loadKeySet()
+	if err != nil {
+		log.Fatalf("Erro ao carregar chaves JWT: %v", err)
+	}
+	auth.SetKeySet(keySet)
+
+	// Inicializa os caches de filmes (padrão: fresco por 6 horas, servido velho por até 7 dias),
+	// de listas calculadas (padrão: 1 hora) e de IDs inexistentes na OMDb (padrão: 10 minutos)
+	movieCache, listCache, failureCache, closeCaches, err := newCaches()
+	if err != nil {
+		log.Fatalf("Erro ao iniciar o cache: %v", err)
+	}
+	defer closeCaches()
 
 	// Cria um cliente para consumir a OMDb API
 	omdbClient := omdb.NewClient(apiKey)
 
+	// Endereço do front end, usado nos links dos emails e no retorno do login social
+	appURL := envOr("APP_URL", "http://localhost:5173")
+
+	// Arquivo onde os usuários são persistidos
+	usersPath := envOr("USERS_DB_PATH", "data/users.json")
+	authBackend := auth.NewFileBackend(usersPath)
+
+	// Atualiza o formato do arquivo antes de carregar os dados
+	if err := authBackend.Migrate(); err != nil {
+		log.Fatalf("Erro ao migrar dados de usuários: %v", err)
+	}
+
+	mailer, err := newMailer()
+	if err != nil {
+		log.Fatalf("Erro ao configurar o envio de emails: %v", err)
+	}
+
 	// Cria o store de autenticação (ex: usuários logados, tokens, etc)
-	authStore := auth.NewStore()
+	authStore, err := auth.NewStore(authBackend, auth.Options{
+		AccessTokenTTL:  durationEnv("ACCESS_TOKEN_TTL"),  // ex: 15m
+		RefreshTokenTTL: durationEnv("REFRESH_TOKEN_TTL"), // ex: 720h
+
+		PasswordResetTTL: durationEnv("PASSWORD_RESET_TTL"),
+		Mailer:           mailer,
+		AppURL:           appURL,
+
+		VerificationTTL:      durationEnv("VERIFICATION_TTL"),
+		RequireVerifiedEmail: os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true",
+
+		LockoutThreshold: intEnv("LOCKOUT_THRESHOLD"),
+		LockoutDuration:  durationEnv("LOCKOUT_DURATION"),
+
+		// Senhas com hash antigo são refeitas com esta política no próximo login
+		PasswordHashing: auth.PasswordHashing{
+			Algorithm:     os.Getenv("PASSWORD_HASH"), // argon2id (padrão) ou bcrypt
+			BcryptCost:    intEnv("BCRYPT_COST"),
+			Argon2Time:    uint32(intEnv("ARGON2_TIME")),
+			Argon2Memory:  uint32(intEnv("ARGON2_MEMORY")), // KiB
+			Argon2Threads: uint8(intEnv("ARGON2_THREADS")),
+		},
+		PasswordPolicy: auth.PasswordPolicy{
+			MinLength:    intEnv("PASSWORD_MIN_LENGTH"),
+			MaxLength:    intEnv("PASSWORD_MAX_LENGTH"),
+			DenylistFile: os.Getenv("PASSWORD_DENYLIST_FILE"), // Uma senha por linha
+			BreachedDir:  os.Getenv("BREACHED_PASSWORDS_DIR"), // Arquivos por prefixo SHA-1 (formato HIBP)
+		},
+
+		// SESSION_COOKIES=true permite ao front end web guardar a sessão em cookies HttpOnly
+		Cookies: auth.CookieOptions{
+			Enabled:  os.Getenv("SESSION_COOKIES") == "true",
+			Secure:   os.Getenv("COOKIE_SECURE") != "false", // Desligar só em desenvolvimento sem HTTPS
+			Domain:   os.Getenv("COOKIE_DOMAIN"),
+			SameSite: os.Getenv("COOKIE_SAMESITE"), // Lax (padrão) ou Strict
+		},
+
+		Registration: os.Getenv("REGISTRATION_MODE"), // open (padrão), invite ou closed
+	})
+	if err != nil {
+		log.Fatalf("Erro ao carregar usuários: %v", err)
+	}
+
+	// Cria (ou promove) o admin inicial definido na configuração
+	if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+		err := authStore.EnsureAdmin(envOr("ADMIN_NAME", "Admin"), adminEmail, os.Getenv("ADMIN_PASSWORD"))
+		if err != nil {
+			log.Fatalf("Erro ao criar admin inicial: %v", err)
+		}
+	}
 
 	// Cria o resolver GraphQL com as dependências injetadas
-	resolver := graphql.NewResolver(cache, omdbClient, authStore)
+	resolver := graphql.NewResolver(movieCache, listCache, failureCache, omdbClient, authStore)
 
 	// Gera o schema GraphQL com base no resolver
 	schema, err := graphql.NewSchema(resolver)
@@ -47,14 +137,64 @@ func main() {
 		log.Fatalf("Erro ao criar schema: %v", err)
 	}
 
+	// Proxies cujo cabeçalho de IP é aceito (PROXY_HEADER exige TRUSTED_PROXIES)
+	proxies, err := trustedProxies()
+	if err != nil {
+		log.Fatalf("Erro ao configurar o proxy: %v", err)
+	}
+
 	// Cria uma instância do servidor Fiber
-	app := fiber.New()
+	// (atrás de proxy, PROXY_HEADER=X-Real-IP faz c.IP() usar o IP real do cliente, mas só
+	// em conexões vindas de TRUSTED_PROXIES; nas outras o cabeçalho é ignorado, para um
+	// cliente não forjar o IP e escapar do limite de tentativas de login)
+	app := fiber.New(fiber.Config{
+		ProxyHeader:             os.Getenv("PROXY_HEADER"),
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          proxies,
+		EnableIPValidation:      true,
+	})
 
 	// Habilita CORS para permitir requisições externas
-	app.Use(cors.New())
+	// (com cookies de sessão o navegador exige a origem exata do front end e credenciais)
+	if authStore.CookiesEnabled() {
+		app.Use(cors.New(cors.Config{
+			AllowOrigins:     strings.TrimSuffix(appURL, "/"),
+			AllowCredentials: true,
+			AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-API-Key, " + auth.CSRFHeader,
+		}))
+	} else {
+		app.Use(cors.New())
+	}
+
+	// Login social (OpenID Connect) com os provedores configurados
+	oidc.NewService(authStore, appURL, loadOIDCProviders()...).Register(app)
+
+	// Publica as chaves públicas para que outros serviços validem nossos tokens
+	app.Get("/.well-known/jwks.json", func(c *fiber.Ctx) error {
+		jwks, err := auth.JWKS()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(jwks)
+	})
+
+	// Liveness: o processo está de pé
+	app.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+
+	// Readiness: só aceita tráfego depois do aquecimento do cache
+	var ready atomic.Bool
+	app.Get("/readyz", func(c *fiber.Ctx) error {
+		if !ready.Load() {
+			return c.Status(503).JSON(fiber.Map{"status": "unavailable"})
+		}
+		return c.JSON(fiber.Map{"status": "ready"})
+	})
 
 	// Define a rota /graphql para receber requisições POST
-	app.Post("/graphql", func(c *fiber.Ctx) error {
+	// (o middleware valida o token JWT e coloca o usuário no context)
+	app.Post("/graphql", auth.Middleware(authStore), func(c *fiber.Ctx) error {
 		// Garante que o Content-Type seja application/json
 		if c.Get("Content-Type") != "application/json" {
 			return c.Status(415).JSON(fiber.Map{"error": "Unsupported Media Type"})
@@ -77,7 +217,7 @@ func main() {
 			Schema:         schema,
 			RequestString:  params.Query,
 			VariableValues: params.Variables,
-			Context:        c.Context(),
+			Context:        c.UserContext(),
 		})
 
 		// Se houver erros de execução, retorna 400 com os erros
@@ -92,6 +232,221 @@ func main() {
 	// Informa no log onde o servidor está rodando
 	log.Println("Servidor rodando em http://localhost:8080/graphql")
 
+	// Com CACHE_WARMUP=true, busca o catálogo inteiro antes de se declarar pronto
+	// (CACHE_WARMUP_CONCURRENCY limita as requisições simultâneas à OMDb, padrão 4)
+	if os.Getenv("CACHE_WARMUP") == "true" {
+		go func() {
+			workers := intEnv("CACHE_WARMUP_CONCURRENCY")
+			if workers <= 0 {
+				workers = 4
+			}
+			start := time.Now()
+			total, failed := resolver.WarmUp(workers)
+			log.Printf("Cache aquecido: %d de %d filmes em %s", total-failed, total, time.Since(start).Round(time.Millisecond))
+			ready.Store(true)
+		}()
+	} else {
+		ready.Store(true)
+	}
+
+	// Ao receber SIGINT/SIGTERM, para de aceitar conexões para que os defers
+	// (como o snapshot do cache) rodem antes de sair
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		log.Println("Encerrando o servidor...")
+		if err := app.Shutdown(); err != nil {
+			log.Printf("Erro ao encerrar o servidor: %v", err)
+		}
+	}()
+
 	// Inicia o servidor na porta 8080 (encerra com erro se falhar)
-	log.Fatal(app.Listen(":8080"))
+	if err := app.Listen(":8080"); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Carrega as chaves JWT de JWT_KEYS_FILE (várias chaves, rotação, RS256/EdDSA)
+// ou, na falta dele, usa JWT_SECRET como única chave HS256
+func loadKeySet() (*auth.KeySet, error) {
+	if path := os.Getenv("JWT_KEYS_FILE"); path != "" {
+		return auth.LoadKeySet(path)
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("defina JWT_KEYS_FILE ou JWT_SECRET")
+	}
+	key, err := auth.NewHMACKey("default", []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewKeySet("default", key)
+}
+
+// Lê uma duração (ex: "15m", "720h") da variável de ambiente; vazia retorna zero
+func durationEnv(key string) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("%s inválida: %v", key, err)
+	}
+	return d
+}
+
+// Lê TRUSTED_PROXIES, IPs ou faixas CIDR separados por vírgula (ex: "10.0.0.5,172.16.0.0/12").
+// O proxy deve sobrescrever o cabeçalho de PROXY_HEADER, não acrescentar a ele: num
+// X-Forwarded-For acrescentado, o primeiro IP ainda vem do cliente.
+func trustedProxies() ([]string, error) {
+	var proxies []string
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if net.ParseIP(entry) == nil {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: %q não é um IP nem uma faixa CIDR", entry)
+			}
+		}
+		proxies = append(proxies, entry)
+	}
+	if os.Getenv("PROXY_HEADER") != "" && len(proxies) == 0 {
+		return nil, errors.New("PROXY_HEADER definido sem TRUSTED_PROXIES")
+	}
+	return proxies, nil
+}
+
+// Lê a variável de ambiente ou retorna o valor padrão
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Escolhe como os emails são entregues: MAIL_DRIVER=smtp envia de verdade,
+// qualquer outro valor grava os emails em MAIL_OUTBOX_DIR
+func newMailer() (mail.Mailer, error) {
+	from := envOr("MAIL_FROM", "CineBase <nao-responda@localhost>")
+	if os.Getenv("MAIL_DRIVER") == "smtp" {
+		return mail.NewSMTPMailer(
+			os.Getenv("SMTP_HOST"),
+			envOr("SMTP_PORT", "587"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			from,
+		)
+	}
+	return mail.NewOutboxMailer(envOr("MAIL_OUTBOX_DIR", "data/outbox"), from), nil
+}
+
+// Escolhe onde filmes e listas ficam em cache: CACHE_DRIVER=redis compartilha o cache
+// entre instâncias; qualquer outro valor usa a memória do processo.
+// Retorna também a função que fecha os caches no encerramento.
+func newCaches() (cache.MovieCache, cache.ListCache, cache.FailureCache, func(), error) {
+	ttl := durationEnv("CACHE_TTL")        // Tempo fresco dos filmes, ex: 6h
+	maxAge := durationEnv("CACHE_MAX_AGE") // Até quando servir o filme velho se a OMDb falhar, ex: 168h
+	listTTL := durationEnv("CACHE_LIST_TTL")
+	if listTTL <= 0 {
+		listTTL = time.Hour
+	}
+	// IDs que a OMDb não conhece ficam pouco tempo: o filme pode ser cadastrado depois
+	failureTTL := durationEnv("CACHE_NEGATIVE_TTL")
+	if failureTTL <= 0 {
+		failureTTL = 10 * time.Minute
+	}
+
+	if os.Getenv("CACHE_DRIVER") == "redis" {
+		client, err := cache.NewRedisClient(cache.RedisOptions{
+			Addr:     os.Getenv("REDIS_ADDR"), // padrão localhost:6379
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       intEnv("REDIS_DB"),
+			Prefix:   os.Getenv("REDIS_PREFIX"), // padrão "cinebase:"
+		})
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		movies := cache.NewRedisCache[string, *model.Movie](client, cache.RedisCacheOptions{Namespace: "movie", TTL: ttl, MaxAge: maxAge})
+		lists := cache.NewRedisCache[string, []*model.Movie](client, cache.RedisCacheOptions{Namespace: "list", TTL: listTTL, MaxAge: listTTL})
+		failures := cache.NewRedisCache[string, cache.Failure](client, cache.RedisCacheOptions{Namespace: "failure", TTL: failureTTL, MaxAge: failureTTL})
+		return movies, lists, failures, client.Close, nil
+	}
+
+	movies := cache.NewMemoryCache[string, *model.Movie](cache.Options{
+		TTL:             ttl,
+		MaxAge:          maxAge,
+		MaxEntries:      intEnv("CACHE_MAX_ENTRIES"), // padrão 10000
+		CleanupInterval: durationEnv("CACHE_CLEANUP_INTERVAL"),
+
+		// Sobrevive a restarts: salvo periodicamente e ao encerrar, recarregado ao subir
+		SnapshotFile:     envOr("CACHE_SNAPSHOT_FILE", "data/movies_cache.json"),
+		SnapshotInterval: durationEnv("CACHE_SNAPSHOT_INTERVAL"), // padrão 15m
+	})
+	// Listas são recalculadas rápido a partir dos filmes: não entram no snapshot
+	lists := cache.NewMemoryCache[string, []*model.Movie](cache.Options{
+		TTL:        listTTL,
+		MaxAge:     listTTL,
+		MaxEntries: 1000, // Chaves por gênero vêm da consulta; o limite evita crescer sem fim
+	})
+	failures := cache.NewMemoryCache[string, cache.Failure](cache.Options{
+		TTL:        failureTTL,
+		MaxAge:     failureTTL,
+		MaxEntries: 1000, // IDs vêm da consulta; o limite evita crescer sem fim
+	})
+	closeAll := func() {
+		failures.Close()
+		lists.Close()
+		movies.Close()
+	}
+	return movies, lists, failures, closeAll, nil
+}
+
+// Lê um inteiro da variável de ambiente; vazia retorna zero
+func intEnv(key string) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("%s inválida: %v", key, err)
+	}
+	return n
+}
+
+// Carrega os provedores listados em OIDC_PROVIDERS (ex: "google,local"); cada um
+// lê OIDC_<NOME>_ISSUER, OIDC_<NOME>_CLIENT_ID e OIDC_<NOME>_CLIENT_SECRET.
+// Provedores fora do ar no boot são ignorados com aviso no log.
+func loadOIDCProviders() []*oidc.Provider {
+	publicURL := strings.TrimSuffix(envOr("PUBLIC_URL", "http://localhost:8080"), "/")
+
+	var providers []*oidc.Provider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		p, err := oidc.NewProvider(ctx, oidc.ProviderConfig{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  publicURL + "/auth/oidc/" + name + "/callback",
+		}, nil)
+		cancel()
+		if err != nil {
+			log.Printf("Provedor OIDC %s desativado: %v", name, err)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return providers
 }
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"movies-api/internal/mail"
+	"movies-api/internal/model"
+)
+
+// Erro para senha atual incorreta nas operações de conta
+var ErrWrongPassword = errors.New("senha atual incorreta")
+
+// Atualiza os dados de perfil do usuário
+func (s *Store) UpdateProfile(userID, name string) (*model.User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("nome não pode ficar vazio")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findByID(userID)
+	if user == nil {
+		return nil, errors.New("usuário não encontrado")
+	}
+
+	updated := *user
+	updated.Name = name
+	s.users[user.Email] = &updated
+	if err := s.persist(); err != nil {
+		s.users[user.Email] = user
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Troca a senha exigindo a atual; as outras sessões do usuário são encerradas
+func (s *Store) ChangePassword(userID, keepSessionID, currentPassword, newPassword string) error {
+	if err := validationError(s.passwords.check("newPassword", newPassword)); err != nil {
+		return err
+	}
+	hashed, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findByID(userID)
+	if user == nil {
+		return errors.New("usuário não encontrado")
+	}
+	if !checkPassword(user, currentPassword) {
+		return ErrWrongPassword
+	}
+
+	updated := *user
+	updated.Password = hashed
+	s.users[user.Email] = &updated
+
+	now := time.Now()
+	var revoked []*model.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.ID != keepSessionID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			revoked = append(revoked, session)
+		}
+	}
+
+	if err := s.persist(); err != nil {
+		s.users[user.Email] = user
+		for _, session := range revoked {
+			session.RevokedAt = nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Inicia a troca de email: o novo endereço só passa a valer depois de confirmado
+// pelo link enviado para ele (via mutation verifyEmail)
+func (s *Store) ChangeEmail(ctx context.Context, userID, newEmail, password string) error {
+	newEmail, invalid := normalizeEmail("newEmail", newEmail)
+	if invalid != nil {
+		return validationError(invalid)
+	}
+
+	s.mu.Lock()
+	user := s.findByID(userID)
+	if user == nil {
+		s.mu.Unlock()
+		return errors.New("usuário não encontrado")
+	}
+	if !checkPassword(user, password) {
+		s.mu.Unlock()
+		return ErrWrongPassword
+	}
+	if _, taken := s.users[newEmail]; taken {
+		s.mu.Unlock()
+		return errEmailTaken("newEmail")
+	}
+
+	token, err := s.issueActionTokenWithData(user.ID, purposeChangeEmail, newEmail, s.opts.VerificationTTL)
+	if err == nil {
+		err = s.persist()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	link := s.opts.AppURL + "/verificar-email?token=" + url.QueryEscape(token)
+	return s.sendMail(ctx, mail.Message{
+		To:      newEmail,
+		Subject: "CineBase - confirme seu novo email",
+		Body: fmt.Sprintf("Olá, %s!\n\nConfirme a troca do email da sua conta abrindo o link abaixo em até %d horas:\n\n%s\n",
+			user.Name, int(s.opts.VerificationTTL.Hours()), link),
+	})
+}
+
+// Aplica uma troca de email confirmada (chamar com o lock de escrita já adquirido)
+func (s *Store) applyEmailChange(user *model.User, newEmail string) (*model.User, error) {
+	if _, taken := s.users[newEmail]; taken {
+		return nil, errEmailTaken("email")
+	}
+
+	updated := *user
+	updated.Email = newEmail
+	updated.EmailVerified = true
+	delete(s.users, user.Email)
+	s.users[newEmail] = &updated
+	return &updated, nil
+}
+
+// Avisa o endereço antigo que o email da conta mudou
+func (s *Store) notifyEmailChanged(ctx context.Context, oldEmail string, user *model.User) {
+	err := s.sendMail(ctx, mail.Message{
+		To:      oldEmail,
+		Subject: "CineBase - email da conta alterado",
+		Body: fmt.Sprintf("Olá, %s!\n\nO email da sua conta foi alterado para %s. "+
+			"Se não foi você, entre em contato com o suporte.\n", user.Name, user.Email),
+	})
+	if err != nil {
+		log.Printf("Erro ao avisar troca de email para %s: %v", oldEmail, err)
+	}
+}
+
+// Exclui a conta e tudo que pertence a ela. Contas sem senha (criadas por login
+// externo) não têm senha para confirmar; nelas basta estar autenticado.
+func (s *Store) DeleteAccount(userID, password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findByID(userID)
+	if user == nil {
+		return errors.New("usuário não encontrado")
+	}
+	if user.Password != "" && !checkPassword(user, password) {
+		return ErrWrongPassword
+	}
+	if user.HasRole(model.RoleAdmin) && s.countAdmins() == 1 {
+		return ErrLastAdmin
+	}
+
+	undo := s.removeUserData(user)
+	if err := s.persist(); err != nil {
+		undo()
+		return err
+	}
+	s.limiter.reset(emailKey(user.Email))
+	return nil
+}
+
+// Remove o usuário e todos os dados ligados a ele; retorna uma função que desfaz a remoção
+// (chamar com o lock de escrita já adquirido). Dados novos ligados a usuários entram aqui.
+func (s *Store) removeUserData(user *model.User) (undo func()) {
+	var undos []func()
+
+	delete(s.users, user.Email)
+	undos = append(undos, func() { s.users[user.Email] = user })
+
+	s.activityMu.Lock()
+	for id, session := range s.sessions {
+		if session.UserID == user.ID {
+			id, session := id, session
+			delete(s.sessions, id)
+			undos = append(undos, func() { s.sessions[id] = session })
+			if act, pending := s.activity[id]; pending {
+				delete(s.activity, id)
+				undos = append(undos, func() {
+					s.activityMu.Lock()
+					s.activity[id] = act
+					s.activityMu.Unlock()
+				})
+			}
+		}
+	}
+	s.activityMu.Unlock()
+	for hash, t := range s.actionTokens {
+		if t.UserID == user.ID {
+			hash, t := hash, t
+			delete(s.actionTokens, hash)
+			undos = append(undos, func() { s.actionTokens[hash] = t })
+		}
+	}
+	for key, link := range s.identities {
+		if link.UserID == user.ID {
+			key, link := key, link
+			delete(s.identities, key)
+			undos = append(undos, func() { s.identities[key] = link })
+		}
+	}
+	for id, k := range s.apiKeys {
+		if k.UserID == user.ID {
+			id, k := id, k
+			delete(s.apiKeys, id)
+			undos = append(undos, func() { s.apiKeys[id] = k })
+			s.activityMu.Lock()
+			if at, pending := s.apiKeyActivity[id]; pending {
+				delete(s.apiKeyActivity, id)
+				undos = append(undos, func() {
+					s.activityMu.Lock()
+					s.apiKeyActivity[id] = at
+					s.activityMu.Unlock()
+				})
+			}
+			s.activityMu.Unlock()
+		}
+	}
+	// Convites continuam valendo para quem já recebeu o código, só perdem o autor
+	for id, inv := range s.invites {
+		if inv.CreatedBy == user.ID {
+			id, inv := id, inv
+			orphan := *inv
+			orphan.CreatedBy = ""
+			s.invites[id] = &orphan
+			undos = append(undos, func() { s.invites[id] = inv })
+		}
+	}
+
+	return func() {
+		for _, u := range undos {
+			u()
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"time"
+
+	"movies-api/internal/model"
+)
+
+// Finalidades dos tokens de uso único
+const (
+	purposePasswordReset = "password_reset"
+	purposeVerifyEmail   = "verify_email"
+	purposeUnlockAccount = "unlock_account"
+	purposeChangeEmail   = "change_email"
+)
+
+// Cria um token de uso único e invalida os anteriores do mesmo usuário e finalidade
+// (chamar com o lock de escrita já adquirido)
+func (s *Store) issueActionToken(userID, purpose string, ttl time.Duration) (string, error) {
+	return s.issueActionTokenWithData(userID, purpose, "", ttl)
+}
+
+// Igual a issueActionToken, guardando um dado extra junto ao token
+func (s *Store) issueActionTokenWithData(userID, purpose, data string, ttl time.Duration) (string, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	for hash, t := range s.actionTokens {
+		if (t.UserID == userID && t.Purpose == purpose) || !now.Before(t.ExpiresAt) {
+			delete(s.actionTokens, hash)
+		}
+	}
+
+	hash := hashSecret(secret)
+	s.actionTokens[hash] = &model.ActionToken{
+		Hash:      hash,
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: now.Add(ttl),
+		Data:      data,
+	}
+	return secret, nil
+}
+
+// Valida e consome um token de uso único (chamar com o lock de escrita já adquirido).
+// O token é removido mesmo que a operação seguinte falhe, por isso quem chama
+// deve restaurá-lo se precisar desfazer.
+func (s *Store) consumeActionToken(secret, purpose string) (*model.ActionToken, bool) {
+	hash := hashSecret(secret)
+	t, exists := s.actionTokens[hash]
+	if !exists || t.Purpose != purpose {
+		return nil, false
+	}
+
+	delete(s.actionTokens, hash)
+	if !time.Now().Before(t.ExpiresAt) {
+		return nil, false
+	}
+	return t, true
+}
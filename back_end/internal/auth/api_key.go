@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"movies-api/internal/model"
+)
+
+// Prefixo que distingue chaves de API de JWTs no header Authorization
+const apiKeyPrefix = "cbk_"
+
+// Intervalo mínimo entre gravações de "último uso" de uma mesma chave
+const apiKeyTouchInterval = time.Minute
+
+// Escopos aceitos na criação de chaves
+var knownScopes = map[string]bool{
+	model.ScopeRead:  true,
+	model.ScopeWrite: true,
+	model.ScopeAdmin: true,
+}
+
+// Erro para chaves inexistentes, revogadas ou expiradas
+var ErrInvalidAPIKey = errors.New("chave de API inválida")
+
+// Indica se o valor enviado pelo cliente tem o formato de chave de API
+func IsAPIKey(raw string) bool {
+	return strings.HasPrefix(raw, apiKeyPrefix)
+}
+
+// Cria uma chave para o usuário e retorna o valor completo, que só é mostrado agora
+func (s *Store) CreateAPIKey(userID, name string, scopes []string, expiresAt *time.Time) (string, *model.APIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", nil, errors.New("nome da chave não pode ficar vazio")
+	}
+	if len(scopes) == 0 {
+		return "", nil, errors.New("informe ao menos um escopo")
+	}
+	for _, sc := range scopes {
+		if !knownScopes[sc] {
+			return "", nil, fmt.Errorf("escopo desconhecido: %s", sc)
+		}
+	}
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return "", nil, errors.New("a expiração precisa estar no futuro")
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, err
+	}
+	secret, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner := s.findByID(userID)
+	if owner == nil {
+		return "", nil, errors.New("usuário não encontrado")
+	}
+	// Escopo admin só faz sentido para quem é admin
+	for _, sc := range scopes {
+		if sc == model.ScopeAdmin && !owner.HasRole(model.RoleAdmin) {
+			return "", nil, errors.New("escopo admin exige um usuário admin")
+		}
+	}
+
+	key := &model.APIKey{
+		ID:        hex.EncodeToString(idBytes),
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Hash:      hashSecret(secret),
+		Scopes:    append([]string{}, scopes...),
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	s.apiKeys[key.ID] = key
+	if err := s.persist(); err != nil {
+		delete(s.apiKeys, key.ID)
+		return "", nil, err
+	}
+
+	copied := *key
+	return apiKeyPrefix + key.ID + "_" + secret, &copied, nil
+}
+
+// Lista as chaves do usuário, das mais novas para as mais antigas
+func (s *Store) ListAPIKeys(userID string) []*model.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []*model.APIKey
+	for _, k := range s.apiKeys {
+		if k.UserID == userID {
+			copied := s.withKeyActivity(k)
+			keys = append(keys, &copied)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// Busca uma chave pelo ID
+func (s *Store) GetAPIKey(id string) (*model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, exists := s.apiKeys[id]
+	if !exists {
+		return nil, errors.New("chave não encontrada")
+	}
+	copied := s.withKeyActivity(k)
+	return &copied, nil
+}
+
+// Revoga (apaga) a chave
+func (s *Store) RevokeAPIKey(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, exists := s.apiKeys[id]
+	if !exists {
+		return errors.New("chave não encontrada")
+	}
+	delete(s.apiKeys, id)
+	if err := s.persist(); err != nil {
+		s.apiKeys[id] = k
+		return err
+	}
+	s.activityMu.Lock()
+	delete(s.apiKeyActivity, id)
+	s.activityMu.Unlock()
+	return nil
+}
+
+// Valida a chave enviada pelo cliente e registra o uso. Roda em toda requisição com
+// chave, então, como em touchSession, só pega o lock de leitura do Store: o uso fica em
+// s.apiKeyActivity e vai para a chave (e para o arquivo) no máximo uma vez por
+// apiKeyTouchInterval.
+func (s *Store) AuthenticateAPIKey(raw string) (*model.User, *model.APIKey, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, apiKeyPrefix), "_")
+	if !ok {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	s.mu.RLock()
+	k, exists := s.apiKeys[id]
+	if !exists || !k.Active(now) {
+		s.mu.RUnlock()
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(k.Hash)) != 1 {
+		s.mu.RUnlock()
+		return nil, nil, ErrInvalidAPIKey
+	}
+	user := s.findByID(k.UserID)
+	if user == nil {
+		s.mu.RUnlock()
+		return nil, nil, ErrInvalidAPIKey
+	}
+	copied := *k
+	s.mu.RUnlock()
+
+	due := copied.LastUsedAt == nil || now.Sub(*copied.LastUsedAt) >= apiKeyTouchInterval
+	s.activityMu.Lock()
+	s.apiKeyActivity[id] = now
+	s.activityMu.Unlock()
+	if due {
+		s.flushKeyActivity(id)
+	}
+
+	copied.LastUsedAt = &now
+	return user, &copied, nil
+}
+
+// Aplica à chave o último uso registrado e grava o arquivo
+func (s *Store) flushKeyActivity(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, exists := s.apiKeys[id]
+	s.activityMu.Lock()
+	at, pending := s.apiKeyActivity[id]
+	// Outra requisição pode ter gravado enquanto esta esperava o lock
+	apply := exists && pending && (k.LastUsedAt == nil || at.Sub(*k.LastUsedAt) >= apiKeyTouchInterval)
+	if apply {
+		delete(s.apiKeyActivity, id)
+	}
+	s.activityMu.Unlock()
+	if !apply {
+		return
+	}
+
+	k.LastUsedAt = &at
+	// Falha aqui só atrasa o registro do uso; não deve derrubar a requisição
+	if err := s.persist(); err != nil {
+		log.Printf("Erro ao registrar uso da chave de API %s: %v", id, err)
+	}
+}
+
+// Retorna a chave com o último uso ainda não gravado, se houver (chamar com o lock já adquirido)
+func (s *Store) withKeyActivity(k *model.APIKey) model.APIKey {
+	copied := *k
+	s.activityMu.Lock()
+	at, pending := s.apiKeyActivity[k.ID]
+	s.activityMu.Unlock()
+	if pending && (copied.LastUsedAt == nil || at.After(*copied.LastUsedAt)) {
+		copied.LastUsedAt = &at
+	}
+	return copied
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"sync"
+
+	"movies-api/internal/model"
+)
+
+// Snapshot contém todos os dados do Store que precisam sobreviver a um restart
+type Snapshot struct {
+	Users        []*model.User
+	Sessions     []*model.Session
+	ActionTokens []*model.ActionToken
+	Identities   []*model.Identity
+	APIKeys      []*model.APIKey
+	Invites      []*model.Invite
+}
+
+// Backend define onde o Store persiste seus dados
+type Backend interface {
+	// Migrate atualiza o formato armazenado para a versão atual
+	Migrate() error
+	// Load lê o estado persistido
+	Load() (*Snapshot, error)
+	// Save grava o estado completo
+	Save(*Snapshot) error
+}
+
+// MemoryBackend mantém os dados apenas em memória (útil para desenvolvimento)
+type MemoryBackend struct {
+	mu       sync.Mutex
+	snapshot *Snapshot
+}
+
+// Cria um backend em memória vazio
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{snapshot: &Snapshot{}}
+}
+
+// Não há nada para migrar em memória
+func (b *MemoryBackend) Migrate() error {
+	return nil
+}
+
+// Retorna o último snapshot salvo
+func (b *MemoryBackend) Load() (*Snapshot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.snapshot, nil
+}
+
+// Substitui o snapshot atual
+func (b *MemoryBackend) Save(s *Snapshot) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.snapshot = s
+	return nil
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+
+	"movies-api/internal/model"
+)
+
+// Chave privada para guardar a identidade no context
+type identityKey struct{}
+
+// Resultado da autenticação de uma requisição
+type identity struct {
+	user      *model.User
+	sessionID string        // Sessão que emitiu o token
+	apiKey    *model.APIKey // Chave usada, quando a requisição veio por chave de API
+	err       error         // Motivo de não haver usuário (token ausente, expirado...)
+}
+
+// Retorna um context com o usuário autenticado e a sessão do token
+func WithUser(ctx context.Context, user *model.User, sessionID string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity{user: user, sessionID: sessionID})
+}
+
+// Retorna um context autenticado por chave de API
+func withAPIKey(ctx context.Context, user *model.User, key *model.APIKey) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity{user: user, apiKey: key})
+}
+
+// Retorna um context registrando por que a requisição não está autenticada
+func withAuthError(ctx context.Context, err error) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity{err: err})
+}
+
+// Recupera o usuário autenticado da requisição
+func UserFromContext(ctx context.Context) (*model.User, error) {
+	id, ok := ctx.Value(identityKey{}).(identity)
+	if !ok {
+		return nil, ErrMissingToken
+	}
+	if id.user == nil {
+		return nil, id.err
+	}
+	return id.user, nil
+}
+
+// Recupera o ID da sessão do usuário autenticado
+func SessionFromContext(ctx context.Context) (string, error) {
+	id, ok := ctx.Value(identityKey{}).(identity)
+	if !ok {
+		return "", ErrMissingToken
+	}
+	if id.user == nil {
+		return "", id.err
+	}
+	return id.sessionID, nil
+}
+
+// Recupera a chave de API usada na requisição (nil para login com sessão)
+func APIKeyFromContext(ctx context.Context) *model.APIKey {
+	id, _ := ctx.Value(identityKey{}).(identity)
+	return id.apiKey
+}
+
+// Dados da conexão do cliente que fez a requisição
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Chave privada para guardar os dados do cliente no context
+type clientKey struct{}
+
+// Retorna um context com os dados do cliente
+func WithClient(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientKey{}, info)
+}
+
+// Recupera os dados do cliente (vazio fora de uma requisição HTTP)
+func ClientFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientKey{}).(ClientInfo)
+	return info
+}
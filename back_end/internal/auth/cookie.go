@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Nomes dos cookies do modo de sessão por cookie
+const (
+	SessionCookie = "cb_session" // Access token (HttpOnly)
+	RefreshCookie = "cb_refresh" // Refresh token (HttpOnly, só enviado para /graphql)
+	CSRFCookie    = "cb_csrf"    // Token CSRF, legível pelo front end para repetir no header
+	CSRFHeader    = "X-CSRF-Token"
+)
+
+// Configuração do modo de sessão por cookie; desativado, só tokens Bearer são aceitos
+type CookieOptions struct {
+	Enabled  bool
+	Secure   bool   // Só envia os cookies por HTTPS
+	Domain   string // Vazio = só o host do back end
+	SameSite string // Lax (padrão) ou Strict
+}
+
+// Preenche os campos não informados com os valores padrão
+func (o CookieOptions) withDefaults() CookieOptions {
+	if o.SameSite == "" {
+		o.SameSite = fiber.CookieSameSiteLaxMode
+	}
+	return o
+}
+
+// Recusa valores de SameSite que deixariam os cookies irem em requisições de outros sites
+func (o CookieOptions) validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	switch strings.ToLower(o.SameSite) {
+	case strings.ToLower(fiber.CookieSameSiteLaxMode), strings.ToLower(fiber.CookieSameSiteStrictMode):
+		return nil
+	}
+	return fmt.Errorf("SameSite dos cookies deve ser Lax ou Strict, não %q", o.SameSite)
+}
+
+// Erros do modo cookie
+var (
+	ErrCookiesDisabled = errors.New("sessão por cookie desativada neste servidor")
+	ErrCSRF            = &CodedError{
+		Code:    "CSRF_FAILED",
+		Message: "token CSRF ausente ou inválido",
+	}
+)
+
+// Estado dos cookies de uma requisição; os resolvers pedem a emissão ou a remoção
+// e o middleware grava os cookies na resposta
+type cookieState struct {
+	refreshToken string     // Valor do cookie de refresh recebido
+	csrfRequired bool       // A requisição trouxe cookies de sessão (um site de terceiros poderia forjá-la)
+	csrfValid    bool       // O header CSRF confere com o cookie
+	issue        *TokenPair // Tokens a gravar nos cookies
+	clear        bool       // Remover os cookies
+}
+
+// Chave privada para guardar o estado dos cookies no context
+type cookieKey struct{}
+
+// Recupera o estado dos cookies (nil quando o modo cookie está desativado)
+func cookiesFromContext(ctx context.Context) *cookieState {
+	state, _ := ctx.Value(cookieKey{}).(*cookieState)
+	return state
+}
+
+// Indica se o servidor aceita sessão por cookie nesta requisição
+func CookiesEnabled(ctx context.Context) bool {
+	return cookiesFromContext(ctx) != nil
+}
+
+// Pede que os tokens sejam entregues em cookies HttpOnly na resposta
+func IssueSessionCookies(ctx context.Context, pair *TokenPair) error {
+	state := cookiesFromContext(ctx)
+	if state == nil {
+		return ErrCookiesDisabled
+	}
+	state.issue, state.clear = pair, false
+	return nil
+}
+
+// Pede a remoção dos cookies de sessão (sem efeito fora do modo cookie)
+func ClearSessionCookies(ctx context.Context) {
+	if state := cookiesFromContext(ctx); state != nil {
+		state.issue, state.clear = nil, true
+	}
+}
+
+// Refresh token recebido no cookie (vazio se não houver)
+func RefreshTokenFromCookie(ctx context.Context) string {
+	if state := cookiesFromContext(ctx); state != nil {
+		return state.refreshToken
+	}
+	return ""
+}
+
+// Exige o token CSRF (double submit) quando a requisição se apoia em cookies de sessão.
+// Requisições com Authorization ou X-API-Key não precisam: outro site não consegue enviar esses headers.
+func CheckCSRF(ctx context.Context) error {
+	state := cookiesFromContext(ctx)
+	if state != nil && state.csrfRequired && !state.csrfValid {
+		return ErrCSRF
+	}
+	return nil
+}
+
+// Lê os cookies da requisição e prepara o estado (chamar só com o modo cookie ativo)
+func readCookies(c *fiber.Ctx, headerAuth bool) *cookieState {
+	state := &cookieState{refreshToken: strings.Clone(c.Cookies(RefreshCookie))}
+	if headerAuth {
+		return state
+	}
+
+	hasSession := c.Cookies(SessionCookie) != "" || state.refreshToken != ""
+	state.csrfRequired = hasSession
+
+	cookie, header := c.Cookies(CSRFCookie), c.Get(CSRFHeader)
+	state.csrfValid = cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+	return state
+}
+
+// Grava ou remove os cookies pedidos pelos resolvers
+func (s *Store) writeCookies(c *fiber.Ctx, state *cookieState) error {
+	switch {
+	case state.issue != nil:
+		return s.WriteSessionCookies(c, state.issue)
+	case state.clear:
+		s.clearCookies(c)
+	}
+	return nil
+}
+
+// Grava os tokens em cookies HttpOnly e um novo token CSRF legível pelo front end
+func (s *Store) WriteSessionCookies(c *fiber.Ctx, pair *TokenPair) error {
+	csrf, err := randomToken()
+	if err != nil {
+		return err
+	}
+	c.Cookie(s.cookie(SessionCookie, pair.AccessToken, "/", pair.ExpiresAt, true))
+	c.Cookie(s.cookie(RefreshCookie, pair.RefreshToken, "/graphql", pair.RefreshExpiresAt, true))
+	c.Cookie(s.cookie(CSRFCookie, csrf, "/", pair.RefreshExpiresAt, false))
+	return nil
+}
+
+// Remove os cookies de sessão (expiração no passado)
+func (s *Store) clearCookies(c *fiber.Ctx) {
+	past := time.Unix(0, 0)
+	c.Cookie(s.cookie(SessionCookie, "", "/", past, true))
+	c.Cookie(s.cookie(RefreshCookie, "", "/graphql", past, true))
+	c.Cookie(s.cookie(CSRFCookie, "", "/", past, false))
+}
+
+// Monta um cookie com as opções configuradas
+func (s *Store) cookie(name, value, path string, expires time.Time, httpOnly bool) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   s.opts.Cookies.Domain,
+		Expires:  expires,
+		Secure:   s.opts.Cookies.Secure,
+		HTTPOnly: httpOnly,
+		SameSite: s.opts.Cookies.SameSite,
+	}
+}
+
+// Indica se o modo de sessão por cookie está ativo
+func (s *Store) CookiesEnabled() bool {
+	return s.opts.Cookies.Enabled
+}
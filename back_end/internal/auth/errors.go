@@ -0,0 +1,38 @@
+package auth
+
+// Erro com código estável para o front end; no GraphQL vai em "extensions"
+type CodedError struct {
+	Code    string
+	Message string
+	Details map[string]interface{} // Dados extras (ex: segundos até nova tentativa)
+}
+
+func (e *CodedError) Error() string {
+	return e.Message
+}
+
+// Extensions implementa gqlerrors.ExtendedError
+func (e *CodedError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code}
+	for k, v := range e.Details {
+		ext[k] = v
+	}
+	return ext
+}
+
+// Login recusado porque o email ainda não foi confirmado
+var ErrEmailNotVerified = &CodedError{
+	Code:    "EMAIL_NOT_VERIFIED",
+	Message: "confirme seu email antes de entrar",
+}
+
+// Usuário autenticado sem o papel exigido pelo campo
+var ErrForbidden = &CodedError{
+	Code:    "FORBIDDEN",
+	Message: "acesso negado",
+}
+
+// Requisição sem autenticação válida; mantém o motivo (token ausente, expirado...) na mensagem
+func Unauthenticated(reason error) error {
+	return &CodedError{Code: "UNAUTHENTICATED", Message: reason.Error()}
+}
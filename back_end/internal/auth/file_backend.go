@@ -0,0 +1,505 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"movies-api/internal/model"
+)
+
+// Versão atual do formato do arquivo de dados
+const fileSchemaVersion = 11
+
+// Documento gravado em disco
+type fileDocument struct {
+	Version    int            `json:"version"`
+	Users      []fileUser     `json:"users"`
+	Sessions   []fileSession  `json:"sessions"`
+	Tokens     []fileToken    `json:"action_tokens"`
+	Identities []fileIdentity `json:"identities"`
+	APIKeys    []fileAPIKey   `json:"api_keys"`
+	Invites    []fileInvite   `json:"invites"`
+}
+
+// Registro de usuário no arquivo (inclui o hash da senha)
+type fileUser struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"password_hash"`
+
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+
+	TwoFactorEnabled bool     `json:"two_factor_enabled"`
+	TOTPSecret       string   `json:"totp_secret,omitempty"`
+	TOTPLastStep     int64    `json:"totp_last_step,omitempty"`
+	RecoveryCodes    []string `json:"recovery_codes,omitempty"` // Hashes
+}
+
+// Registro de sessão no arquivo (inclui o hash do refresh token)
+type fileSession struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	RefreshHash string     `json:"refresh_hash"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Registro de token de uso único no arquivo
+type fileToken struct {
+	Hash      string    `json:"hash"`
+	UserID    string    `json:"user_id"`
+	Purpose   string    `json:"purpose"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Data      string    `json:"data,omitempty"`
+}
+
+// Registro de identidade externa no arquivo
+type fileIdentity struct {
+	Issuer   string    `json:"issuer"`
+	Subject  string    `json:"subject"`
+	UserID   string    `json:"user_id"`
+	Email    string    `json:"email"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// Registro de chave de API no arquivo (inclui o hash do segredo)
+type fileAPIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"hash"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Registro de convite de cadastro no arquivo (inclui o hash do código)
+type fileInvite struct {
+	ID        string     `json:"id"`
+	Hash      string     `json:"hash"`
+	CreatedBy string     `json:"created_by"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Cada migração leva o documento da versão anterior para a sua versão
+type fileMigration struct {
+	version int
+	up      func(doc *fileDocument) error
+}
+
+// Migrações aplicadas em ordem; novas versões entram no fim da lista
+var fileMigrations = []fileMigration{
+	{
+		version: 1,
+		up: func(doc *fileDocument) error {
+			if doc.Users == nil {
+				doc.Users = []fileUser{}
+			}
+			return nil
+		},
+	},
+	{
+		// v2: sessões de login com refresh token
+		version: 2,
+		up: func(doc *fileDocument) error {
+			if doc.Sessions == nil {
+				doc.Sessions = []fileSession{}
+			}
+			return nil
+		},
+	},
+	{
+		// v3: tokens de uso único (redefinição de senha)
+		version: 3,
+		up: func(doc *fileDocument) error {
+			if doc.Tokens == nil {
+				doc.Tokens = []fileToken{}
+			}
+			return nil
+		},
+	},
+	{
+		// v4: verificação de email; contas anteriores à verificação são consideradas confirmadas
+		version: 4,
+		up: func(doc *fileDocument) error {
+			for i := range doc.Users {
+				doc.Users[i].EmailVerified = true
+			}
+			return nil
+		},
+	},
+	{
+		// v5: papéis; contas existentes viram usuários comuns
+		version: 5,
+		up: func(doc *fileDocument) error {
+			for i := range doc.Users {
+				if len(doc.Users[i].Roles) == 0 {
+					doc.Users[i].Roles = []string{model.RoleUser}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// v6: identidades de provedores OpenID Connect
+		version: 6,
+		up: func(doc *fileDocument) error {
+			if doc.Identities == nil {
+				doc.Identities = []fileIdentity{}
+			}
+			return nil
+		},
+	},
+	{
+		// v7: chaves de API
+		version: 7,
+		up: func(doc *fileDocument) error {
+			if doc.APIKeys == nil {
+				doc.APIKeys = []fileAPIKey{}
+			}
+			return nil
+		},
+	},
+	{
+		// v8: autenticação em dois fatores; os campos novos começam desativados
+		version: 8,
+		up: func(doc *fileDocument) error {
+			return nil
+		},
+	},
+	{
+		// v9: dispositivo e último acesso das sessões; sessões antigas contam o login como último acesso
+		version: 9,
+		up: func(doc *fileDocument) error {
+			for i := range doc.Sessions {
+				if doc.Sessions[i].LastSeenAt.IsZero() {
+					doc.Sessions[i].LastSeenAt = doc.Sessions[i].CreatedAt
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// v10: emails em minúsculas. Contas que só diferem em maiúsculas precisam ser
+		// unificadas à mão antes, porque não dá para escolher qual delas manter.
+		version: 10,
+		up: func(doc *fileDocument) error {
+			seen := make(map[string]string)
+			for i := range doc.Users {
+				email := canonicalEmail(doc.Users[i].Email)
+				if other, dup := seen[email]; dup {
+					return fmt.Errorf("as contas %s e %s só diferem em maiúsculas; unifique-as antes de atualizar", other, doc.Users[i].Email)
+				}
+				seen[email] = doc.Users[i].Email
+				doc.Users[i].Email = email
+			}
+			for i := range doc.Identities {
+				doc.Identities[i].Email = canonicalEmail(doc.Identities[i].Email)
+			}
+			// Trocas de email pendentes guardam o novo endereço no token
+			for i := range doc.Tokens {
+				if doc.Tokens[i].Purpose == purposeChangeEmail {
+					doc.Tokens[i].Data = canonicalEmail(doc.Tokens[i].Data)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// v11: convites de cadastro
+		version: 11,
+		up: func(doc *fileDocument) error {
+			if doc.Invites == nil {
+				doc.Invites = []fileInvite{}
+			}
+			return nil
+		},
+	},
+}
+
+// FileBackend persiste os dados em um arquivo JSON local
+type FileBackend struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Cria um backend que grava no caminho informado
+func NewFileBackend(path string) *FileBackend {
+	return &FileBackend{path: path}
+}
+
+// Aplica as migrações pendentes e grava o arquivo na versão atual
+func (b *FileBackend) Migrate() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	doc, err := b.read()
+	if err != nil {
+		return err
+	}
+
+	if doc.Version > fileSchemaVersion {
+		return fmt.Errorf("arquivo %s está na versão %d, mais nova que a suportada (%d)", b.path, doc.Version, fileSchemaVersion)
+	}
+
+	start := doc.Version
+	for _, m := range fileMigrations {
+		if m.version <= doc.Version {
+			continue
+		}
+		if err := m.up(doc); err != nil {
+			return fmt.Errorf("migração %d falhou: %w", m.version, err)
+		}
+		doc.Version = m.version
+	}
+
+	// Só reescreve o arquivo se algo mudou
+	if doc.Version == start {
+		return nil
+	}
+	return b.write(doc)
+}
+
+// Lê o arquivo e converte para Snapshot
+func (b *FileBackend) Load() (*Snapshot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	doc, err := b.read()
+	if err != nil {
+		return nil, err
+	}
+	if doc.Version != fileSchemaVersion {
+		return nil, fmt.Errorf("arquivo %s na versão %d; execute Migrate antes de carregar", b.path, doc.Version)
+	}
+
+	snap := &Snapshot{}
+	for _, u := range doc.Users {
+		snap.Users = append(snap.Users, &model.User{
+			ID:       u.ID,
+			Name:     u.Name,
+			Email:    u.Email,
+			Password: u.PasswordHash,
+
+			EmailVerified: u.EmailVerified,
+			Roles:         u.Roles,
+
+			TwoFactorEnabled: u.TwoFactorEnabled,
+			TOTPSecret:       u.TOTPSecret,
+			TOTPLastStep:     u.TOTPLastStep,
+			RecoveryCodes:    u.RecoveryCodes,
+		})
+	}
+	for _, fs := range doc.Sessions {
+		snap.Sessions = append(snap.Sessions, &model.Session{
+			ID:          fs.ID,
+			UserID:      fs.UserID,
+			RefreshHash: fs.RefreshHash,
+			CreatedAt:   fs.CreatedAt,
+			ExpiresAt:   fs.ExpiresAt,
+			RevokedAt:   fs.RevokedAt,
+
+			UserAgent:  fs.UserAgent,
+			IP:         fs.IP,
+			LastSeenAt: fs.LastSeenAt,
+		})
+	}
+	for _, ft := range doc.Tokens {
+		snap.ActionTokens = append(snap.ActionTokens, &model.ActionToken{
+			Hash:      ft.Hash,
+			UserID:    ft.UserID,
+			Purpose:   ft.Purpose,
+			ExpiresAt: ft.ExpiresAt,
+			Data:      ft.Data,
+		})
+	}
+	for _, fi := range doc.Identities {
+		snap.Identities = append(snap.Identities, &model.Identity{
+			Issuer:   fi.Issuer,
+			Subject:  fi.Subject,
+			UserID:   fi.UserID,
+			Email:    fi.Email,
+			LinkedAt: fi.LinkedAt,
+		})
+	}
+	for _, fk := range doc.APIKeys {
+		snap.APIKeys = append(snap.APIKeys, &model.APIKey{
+			ID:         fk.ID,
+			UserID:     fk.UserID,
+			Name:       fk.Name,
+			Hash:       fk.Hash,
+			Scopes:     fk.Scopes,
+			CreatedAt:  fk.CreatedAt,
+			ExpiresAt:  fk.ExpiresAt,
+			LastUsedAt: fk.LastUsedAt,
+		})
+	}
+	for _, fi := range doc.Invites {
+		snap.Invites = append(snap.Invites, &model.Invite{
+			ID:        fi.ID,
+			Hash:      fi.Hash,
+			CreatedBy: fi.CreatedBy,
+			MaxUses:   fi.MaxUses,
+			Uses:      fi.Uses,
+			CreatedAt: fi.CreatedAt,
+			ExpiresAt: fi.ExpiresAt,
+			RevokedAt: fi.RevokedAt,
+		})
+	}
+	return snap, nil
+}
+
+// Converte o Snapshot e grava no arquivo
+func (b *FileBackend) Save(s *Snapshot) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	doc := &fileDocument{
+		Version:    fileSchemaVersion,
+		Users:      []fileUser{},
+		Sessions:   []fileSession{},
+		Tokens:     []fileToken{},
+		Identities: []fileIdentity{},
+		APIKeys:    []fileAPIKey{},
+		Invites:    []fileInvite{},
+	}
+	for _, u := range s.Users {
+		doc.Users = append(doc.Users, fileUser{
+			ID:           u.ID,
+			Name:         u.Name,
+			Email:        u.Email,
+			PasswordHash: u.Password,
+
+			EmailVerified: u.EmailVerified,
+			Roles:         u.Roles,
+
+			TwoFactorEnabled: u.TwoFactorEnabled,
+			TOTPSecret:       u.TOTPSecret,
+			TOTPLastStep:     u.TOTPLastStep,
+			RecoveryCodes:    u.RecoveryCodes,
+		})
+	}
+	for _, sess := range s.Sessions {
+		doc.Sessions = append(doc.Sessions, fileSession{
+			ID:          sess.ID,
+			UserID:      sess.UserID,
+			RefreshHash: sess.RefreshHash,
+			CreatedAt:   sess.CreatedAt,
+			ExpiresAt:   sess.ExpiresAt,
+			RevokedAt:   sess.RevokedAt,
+
+			UserAgent:  sess.UserAgent,
+			IP:         sess.IP,
+			LastSeenAt: sess.LastSeenAt,
+		})
+	}
+	for _, t := range s.ActionTokens {
+		doc.Tokens = append(doc.Tokens, fileToken{
+			Hash:      t.Hash,
+			UserID:    t.UserID,
+			Purpose:   t.Purpose,
+			ExpiresAt: t.ExpiresAt,
+			Data:      t.Data,
+		})
+	}
+	for _, id := range s.Identities {
+		doc.Identities = append(doc.Identities, fileIdentity{
+			Issuer:   id.Issuer,
+			Subject:  id.Subject,
+			UserID:   id.UserID,
+			Email:    id.Email,
+			LinkedAt: id.LinkedAt,
+		})
+	}
+	for _, k := range s.APIKeys {
+		doc.APIKeys = append(doc.APIKeys, fileAPIKey{
+			ID:         k.ID,
+			UserID:     k.UserID,
+			Name:       k.Name,
+			Hash:       k.Hash,
+			Scopes:     k.Scopes,
+			CreatedAt:  k.CreatedAt,
+			ExpiresAt:  k.ExpiresAt,
+			LastUsedAt: k.LastUsedAt,
+		})
+	}
+	for _, inv := range s.Invites {
+		doc.Invites = append(doc.Invites, fileInvite{
+			ID:        inv.ID,
+			Hash:      inv.Hash,
+			CreatedBy: inv.CreatedBy,
+			MaxUses:   inv.MaxUses,
+			Uses:      inv.Uses,
+			CreatedAt: inv.CreatedAt,
+			ExpiresAt: inv.ExpiresAt,
+			RevokedAt: inv.RevokedAt,
+		})
+	}
+	return b.write(doc)
+}
+
+// Lê o documento do disco; arquivo inexistente equivale à versão 0
+func (b *FileBackend) read() (*fileDocument, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &fileDocument{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc fileDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("arquivo %s inválido: %w", b.path, err)
+	}
+	return &doc, nil
+}
+
+// Grava em um arquivo temporário e renomeia, para nunca deixar o arquivo pela metade
+func (b *FileBackend) write(doc *fileDocument) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(b.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(b.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Sem efeito se o rename der certo
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path)
+}
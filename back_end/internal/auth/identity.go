@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"movies-api/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// Dados de um usuário autenticado por um provedor externo
+type ExternalIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool // O provedor garante que o email é do usuário
+	Name          string
+}
+
+// Chave do mapa de identidades: o "sub" só é único dentro do issuer
+func identityKeyOf(issuer, subject string) string {
+	return issuer + "|" + subject
+}
+
+// Encontra o usuário vinculado à identidade externa. Na primeira vez, vincula a uma
+// conta com o mesmo email (só se o provedor verificou o email) ou cria uma conta nova.
+func (s *Store) LoginWithIdentity(ext ExternalIdentity) (*model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := identityKeyOf(ext.Issuer, ext.Subject)
+	if link, exists := s.identities[key]; exists {
+		user := s.findByID(link.UserID)
+		if user == nil {
+			return nil, errors.New("usuário não encontrado")
+		}
+		return s.checkIdentityLogin(user)
+	}
+
+	if ext.Email == "" {
+		return nil, errors.New("o provedor não informou um email")
+	}
+	email, invalid := normalizeEmail("email", ext.Email)
+	if invalid != nil {
+		return nil, errors.New("o provedor informou um email inválido")
+	}
+	ext.Email = email
+
+	previous, exists := s.users[ext.Email]
+	var user *model.User
+	switch {
+	case exists && !ext.EmailVerified:
+		// Sem verificação, qualquer um poderia tomar a conta criando um login externo com o mesmo email
+		return nil, errors.New("já existe uma conta com este email; entre com sua senha")
+	case exists:
+		// O provedor confirmou o email, então a conta local também fica verificada
+		updated := *previous
+		updated.EmailVerified = true
+		user = &updated
+	case s.opts.Registration != RegistrationOpen:
+		// Login externo não tem como apresentar convite; fora do modo aberto só entra quem já tem conta
+		return nil, ErrRegistrationClosed
+	default:
+		name := ext.Name
+		if name == "" {
+			name, _, _ = strings.Cut(ext.Email, "@")
+		}
+		// Sem senha: a conta só entra pelo provedor até o usuário definir uma senha
+		user = &model.User{
+			ID:            uuid.New().String(),
+			Name:          name,
+			Email:         ext.Email,
+			EmailVerified: ext.EmailVerified,
+			Roles:         []string{model.RoleUser},
+		}
+	}
+
+	s.users[user.Email] = user
+	s.identities[key] = &model.Identity{
+		Issuer:   ext.Issuer,
+		Subject:  ext.Subject,
+		UserID:   user.ID,
+		Email:    ext.Email,
+		LinkedAt: time.Now(),
+	}
+	if err := s.persist(); err != nil {
+		delete(s.identities, key)
+		if exists {
+			s.users[user.Email] = previous
+		} else {
+			delete(s.users, user.Email)
+		}
+		return nil, err
+	}
+	return s.checkIdentityLogin(user)
+}
+
+// Aplica ao login externo as mesmas regras do login com senha
+func (s *Store) checkIdentityLogin(user *model.User) (*model.User, error) {
+	if s.opts.RequireVerifiedEmail && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	return user, nil
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"movies-api/internal/model"
+)
+
+// Modos de cadastro pela mutation signup
+const (
+	RegistrationOpen   = "open"   // Qualquer um pode se cadastrar
+	RegistrationInvite = "invite" // Cadastro só com código de convite
+	RegistrationClosed = "closed" // Ninguém se cadastra (contas só pelo admin inicial)
+)
+
+// Máximo de usos de um convite, para um código vazado não abrir o cadastro de vez
+const maxInviteUses = 1000
+
+// Erros de cadastro por modo
+var (
+	ErrRegistrationClosed = &CodedError{
+		Code:    "REGISTRATION_CLOSED",
+		Message: "cadastro fechado",
+	}
+	ErrInviteNotFound = errors.New("convite não encontrado")
+)
+
+// Confere se o modo de cadastro é conhecido
+func validRegistrationMode(mode string) error {
+	switch mode {
+	case RegistrationOpen, RegistrationInvite, RegistrationClosed:
+		return nil
+	}
+	return fmt.Errorf("modo de cadastro desconhecido: %q", mode)
+}
+
+// Modo de cadastro configurado (para o front end decidir se pede o convite)
+func (s *Store) RegistrationMode() string {
+	return s.opts.Registration
+}
+
+// Cria um convite com o número de usos e a validade informados; retorna o código,
+// que só é mostrado agora
+func (s *Store) CreateInvite(createdBy string, maxUses int, expiresAt *time.Time) (string, *model.Invite, error) {
+	if maxUses < 1 || maxUses > maxInviteUses {
+		return "", nil, fmt.Errorf("o convite deve permitir de 1 a %d usos", maxInviteUses)
+	}
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return "", nil, errors.New("a expiração precisa estar no futuro")
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, err
+	}
+	code, err := newInviteCode()
+	if err != nil {
+		return "", nil, err
+	}
+
+	invite := &model.Invite{
+		ID:        hex.EncodeToString(idBytes),
+		Hash:      hashSecret(normalizeInviteCode(code)),
+		CreatedBy: createdBy,
+		MaxUses:   maxUses,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.invites[invite.ID] = invite
+	if err := s.persist(); err != nil {
+		delete(s.invites, invite.ID)
+		return "", nil, err
+	}
+	copied := *invite
+	return code, &copied, nil
+}
+
+// Lista os convites, dos mais novos para os mais antigos
+func (s *Store) ListInvites() []*model.Invite {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*model.Invite, 0, len(s.invites))
+	for _, inv := range s.invites {
+		copied := *inv
+		list = append(list, &copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Revoga um convite; cadastros já feitos com ele continuam valendo
+func (s *Store) RevokeInvite(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv, exists := s.invites[id]
+	if !exists {
+		return ErrInviteNotFound
+	}
+	if inv.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	inv.RevokedAt = &now
+	if err := s.persist(); err != nil {
+		inv.RevokedAt = nil
+		return err
+	}
+	return nil
+}
+
+// Confere o modo de cadastro e, no modo convite, reserva um uso do convite.
+// Chamar com o lock de escrita já adquirido; o uso é gravado junto com a conta
+// no mesmo persist, e a função retornada desfaz a reserva se a gravação falhar.
+func (s *Store) admitSignup(code string) (undo func(), err error) {
+	switch s.opts.Registration {
+	case RegistrationClosed:
+		return nil, ErrRegistrationClosed
+	case RegistrationOpen:
+		return func() {}, nil
+	}
+
+	invalid := validationError(&FieldError{Field: "inviteCode", Code: "INVALID_INVITE", Message: "convite inválido, expirado ou esgotado"})
+	if strings.TrimSpace(code) == "" {
+		return nil, validationError(&FieldError{Field: "inviteCode", Code: "REQUIRED", Message: "informe o código de convite"})
+	}
+
+	hash := hashSecret(normalizeInviteCode(code))
+	for _, inv := range s.invites {
+		if inv.Hash != hash {
+			continue
+		}
+		if !inv.Active(time.Now()) {
+			return nil, invalid
+		}
+		inv.Uses++
+		return func() { inv.Uses-- }, nil
+	}
+	return nil, invalid
+}
+
+// Gera um código fácil de digitar no formato XXXX-XXXX-XXXX-XXXX (80 bits)
+func newInviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := totpEncoding.EncodeToString(b)
+	return raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16], nil
+}
+
+// Aceita o código com ou sem hífens, em maiúsculas ou minúsculas
+func normalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
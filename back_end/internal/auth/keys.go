@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key é uma chave de assinatura/verificação identificada pelo "kid"
+type Key struct {
+	ID     string            // Valor do header "kid"
+	Method jwt.SigningMethod // HS256, RS256 ou EdDSA
+	sign   interface{}       // Chave usada para assinar (nil = só verificação)
+	verify interface{}       // Chave usada para verificar
+}
+
+// KeySet agrupa a chave ativa (que assina) e as chaves aceitas na verificação
+type KeySet struct {
+	active string
+	keys   map[string]*Key
+}
+
+// Conjunto de chaves usado por GenerateToken e ParseToken
+var (
+	keysMu sync.RWMutex
+	keys   *KeySet
+)
+
+// Define o conjunto de chaves usado pelo pacote
+func SetKeySet(ks *KeySet) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keys = ks
+}
+
+// Retorna o conjunto de chaves configurado
+func currentKeySet() (*KeySet, error) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	if keys == nil {
+		return nil, errors.New("chaves JWT não configuradas")
+	}
+	return keys, nil
+}
+
+// Cria um KeySet; a chave ativa precisa ter parte privada
+func NewKeySet(active string, list ...*Key) (*KeySet, error) {
+	ks := &KeySet{active: active, keys: make(map[string]*Key)}
+	for _, k := range list {
+		if _, dup := ks.keys[k.ID]; dup {
+			return nil, fmt.Errorf("kid %q repetido", k.ID)
+		}
+		ks.keys[k.ID] = k
+	}
+
+	k, ok := ks.keys[active]
+	if !ok {
+		return nil, fmt.Errorf("chave ativa %q não encontrada", active)
+	}
+	if k.sign == nil {
+		return nil, fmt.Errorf("chave ativa %q não tem chave privada", active)
+	}
+	return ks, nil
+}
+
+// Cria uma chave HS256 a partir de um segredo compartilhado
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("segredo da chave %q precisa ter ao menos 32 bytes", id)
+	}
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, sign: secret, verify: secret}, nil
+}
+
+// Cria uma chave RS256 ou EdDSA a partir de uma chave privada
+func NewPrivateKey(id string, priv crypto.Signer) (*Key, error) {
+	switch p := priv.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, sign: p, verify: &p.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, sign: p, verify: p.Public()}, nil
+	}
+	return nil, fmt.Errorf("tipo de chave privada não suportado em %q", id)
+}
+
+// Cria uma chave apenas de verificação (ex: chave antiga ainda em rotação)
+func NewPublicKey(id string, pub crypto.PublicKey) (*Key, error) {
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, verify: p}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, verify: p}, nil
+	}
+	return nil, fmt.Errorf("tipo de chave pública não suportado em %q", id)
+}
+
+// Formato do arquivo de configuração de chaves
+type keyFile struct {
+	Active string `json:"active"` // kid usado para assinar novos tokens
+	Keys   []struct {
+		ID             string `json:"kid"`
+		Alg            string `json:"alg"`
+		Secret         string `json:"secret"`           // HS256
+		SecretEnv      string `json:"secret_env"`       // HS256, lido de variável de ambiente
+		PrivateKeyFile string `json:"private_key_file"` // RS256/EdDSA, PEM PKCS#8 ou PKCS#1
+		PublicKeyFile  string `json:"public_key_file"`  // RS256/EdDSA só para verificação, PEM PKIX
+	} `json:"keys"`
+}
+
+// Carrega um KeySet de um arquivo JSON; caminhos relativos partem da pasta do arquivo
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg keyFile
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("arquivo de chaves inválido: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	var list []*Key
+	for _, kc := range cfg.Keys {
+		var key *Key
+		switch {
+		case kc.Alg == "HS256":
+			secret := kc.Secret
+			if kc.SecretEnv != "" {
+				secret = os.Getenv(kc.SecretEnv)
+			}
+			key, err = NewHMACKey(kc.ID, []byte(secret))
+		case kc.PrivateKeyFile != "":
+			var priv crypto.Signer
+			if priv, err = readPrivateKey(resolve(kc.PrivateKeyFile)); err == nil {
+				key, err = NewPrivateKey(kc.ID, priv)
+			}
+		case kc.PublicKeyFile != "":
+			var pub crypto.PublicKey
+			if pub, err = readPublicKey(resolve(kc.PublicKeyFile)); err == nil {
+				key, err = NewPublicKey(kc.ID, pub)
+			}
+		default:
+			err = fmt.Errorf("chave %q sem segredo nem arquivo PEM", kc.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// O alg declarado precisa bater com o tipo da chave lida
+		if key.Method.Alg() != kc.Alg {
+			return nil, fmt.Errorf("chave %q declarada como %s mas é %s", kc.ID, kc.Alg, key.Method.Alg())
+		}
+		list = append(list, key)
+	}
+
+	return NewKeySet(cfg.Active, list...)
+}
+
+// Lê uma chave privada PEM (PKCS#8 ou PKCS#1)
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	signer, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: chave privada não suportada", path)
+	}
+	return signer, nil
+}
+
+// Lê uma chave pública PEM (PKIX)
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	k, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return k, nil
+}
+
+// Lê o primeiro bloco PEM do arquivo
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: nenhum bloco PEM encontrado", path)
+	}
+	return block, nil
+}
+
+// Chave usada para assinar novos tokens
+func (ks *KeySet) signingKey() *Key {
+	return ks.keys[ks.active]
+}
+
+// Resolve a chave de verificação pelo "kid" do header
+func (ks *KeySet) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("kid %q desconhecido", kid)
+	}
+	// Impede que o cliente troque o algoritmo da chave
+	if t.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("algoritmo %s não corresponde à chave %q", t.Method.Alg(), kid)
+	}
+	return k.verify, nil
+}
+
+// Algoritmos aceitos na verificação
+func (ks *KeySet) methods() []string {
+	seen := make(map[string]bool)
+	var algs []string
+	for _, k := range ks.keys {
+		if !seen[k.Method.Alg()] {
+			seen[k.Method.Alg()] = true
+			algs = append(algs, k.Method.Alg())
+		}
+	}
+	return algs
+}
+
+// JWKS retorna as chaves públicas no formato JSON Web Key Set.
+// Chaves HS256 são segredos compartilhados e nunca são publicadas.
+func JWKS() (map[string]interface{}, error) {
+	ks, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids) // Ordem estável na resposta
+
+	list := []map[string]interface{}{}
+	for _, id := range ids {
+		k := ks.keys[id]
+		b64 := base64.RawURLEncoding.EncodeToString
+		switch pub := k.verify.(type) {
+		case *rsa.PublicKey:
+			list = append(list, map[string]interface{}{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": k.Method.Alg(),
+				"kid": k.ID,
+				"n":   b64(pub.N.Bytes()),
+				"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			list = append(list, map[string]interface{}{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"use": "sig",
+				"alg": k.Method.Alg(),
+				"kid": k.ID,
+				"x":   b64(pub),
+			})
+		}
+	}
+	return map[string]interface{}{"keys": list}, nil
+}
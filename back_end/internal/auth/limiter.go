@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Regras de tentativas para um tipo de chave (email ou IP)
+type attemptPolicy struct {
+	freeAttempts int           // Falhas toleradas antes de começar a espera
+	baseDelay    time.Duration // Espera após a primeira falha além das toleradas; dobra a cada nova falha
+	maxDelay     time.Duration // Teto da espera progressiva
+	lockAfter    int           // Falhas até bloquear a chave (0 = nunca bloqueia)
+	lockFor      time.Duration // Duração do bloqueio
+	window       time.Duration // Falhas mais antigas que isso são esquecidas
+}
+
+// Estado de tentativas de uma chave
+type attemptState struct {
+	failures    int
+	lastFailure time.Time
+	nextAllowed time.Time // Espera progressiva
+	lockedUntil time.Time // Bloqueio temporário
+}
+
+// Limiter conta falhas de login em memória e decide quando recusar novas tentativas
+type Limiter struct {
+	mu        sync.Mutex
+	states    map[string]*attemptState
+	lastSweep time.Time
+}
+
+// Cria um Limiter vazio
+func NewLimiter() *Limiter {
+	return &Limiter{states: make(map[string]*attemptState)}
+}
+
+// Confere a espera e o bloqueio da chave e, se a tentativa puder seguir, já a conta
+// como falha sob o mesmo lock, para tentativas simultâneas não passarem todas antes da
+// primeira falha ser registrada; quem acertar desfaz com reset ou release.
+// Retorna a espera restante (> 0 recusa a tentativa), se a chave está bloqueada e se
+// esta tentativa é a que a bloqueia caso falhe.
+func (l *Limiter) reserve(key string, p attemptPolicy, now time.Time) (wait time.Duration, locked, locks bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if st, ok := l.states[key]; ok {
+		if now.Before(st.lockedUntil) {
+			return st.lockedUntil.Sub(now), true, false
+		}
+		if now.Before(st.nextAllowed) {
+			return st.nextAllowed.Sub(now), false, false
+		}
+	}
+	return 0, false, l.fail(key, p, now)
+}
+
+// Registra uma falha; retorna true se esta falha acabou de bloquear a chave
+// (chamar com o lock já adquirido)
+func (l *Limiter) fail(key string, p attemptPolicy, now time.Time) bool {
+	l.sweep(now, p.window)
+
+	st, ok := l.states[key]
+	if !ok || now.Sub(st.lastFailure) > p.window {
+		st = &attemptState{}
+		l.states[key] = st
+	}
+
+	// Uma falha depois de um bloqueio vencido recomeça a contagem do zero
+	if !st.lockedUntil.IsZero() && !now.Before(st.lockedUntil) {
+		*st = attemptState{}
+	}
+
+	st.failures++
+	st.lastFailure = now
+
+	if extra := st.failures - p.freeAttempts; extra > 0 {
+		delay := time.Duration(float64(p.baseDelay) * math.Pow(2, float64(extra-1)))
+		if delay > p.maxDelay || delay <= 0 {
+			delay = p.maxDelay
+		}
+		st.nextAllowed = now.Add(delay)
+	}
+
+	if p.lockAfter > 0 && st.failures >= p.lockAfter {
+		st.lockedUntil = now.Add(p.lockFor)
+		return true
+	}
+	return false
+}
+
+// Devolve uma tentativa reservada que deu certo sem esquecer as outras falhas
+// (usado no IP, que não deve ser zerado por um login correto)
+func (l *Limiter) release(key string, p attemptPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st, ok := l.states[key]
+	if !ok {
+		return
+	}
+	st.failures--
+	if st.failures <= 0 && st.lockedUntil.IsZero() {
+		delete(l.states, key)
+		return
+	}
+	if st.failures <= p.freeAttempts {
+		st.nextAllowed = time.Time{}
+	}
+}
+
+// Esquece as falhas da chave (login com sucesso ou desbloqueio)
+func (l *Limiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.states, key)
+}
+
+// Remove chaves sem falhas recentes para o mapa não crescer sem limite
+// (chamar com o lock já adquirido; roda no máximo uma vez por minuto)
+func (l *Limiter) sweep(now time.Time, window time.Duration) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, st := range l.states {
+		if now.Sub(st.lastFailure) > window && !now.Before(st.lockedUntil) {
+			delete(l.states, key)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"time"
+
+	"movies-api/internal/mail"
+)
+
+// Chaves usadas no Limiter
+func emailKey(email string) string { return "email:" + email }
+func ipKey(ip string) string       { return "ip:" + ip }
+
+// Regras por email: espera progressiva e bloqueio temporário da conta
+func (s *Store) emailPolicy() attemptPolicy {
+	return attemptPolicy{
+		freeAttempts: 3,
+		baseDelay:    time.Second,
+		maxDelay:     time.Minute,
+		lockAfter:    s.opts.LockoutThreshold,
+		lockFor:      s.opts.LockoutDuration,
+		window:       time.Hour,
+	}
+}
+
+// Regras por IP: só espera progressiva, para um IP compartilhado não bloquear contas alheias
+func (s *Store) ipPolicy() attemptPolicy {
+	return attemptPolicy{
+		freeAttempts: 20,
+		baseDelay:    time.Second,
+		maxDelay:     5 * time.Minute,
+		window:       time.Hour,
+	}
+}
+
+// Tentativa de login já contada como falha no Limiter, antes de conferir a senha
+type attempt struct {
+	email, ip string
+	locks     bool // Se falhar, esta tentativa é a que bloqueia a conta
+}
+
+// Recusa a tentativa se o IP ou o email ainda estiver em espera ou se o email estiver
+// bloqueado; senão a reserva, contando-a como falha até succeeded dizer o contrário.
+// O IP vem primeiro porque a reserva no email pode bloquear a conta, e esse bloqueio
+// só pode acontecer numa tentativa que de fato segue (e que manda o email de desbloqueio).
+func (s *Store) beginAttempt(email, ip string, now time.Time) (*attempt, error) {
+	if ip != "" {
+		if wait, _, _ := s.limiter.reserve(ipKey(ip), s.ipPolicy(), now); wait > 0 {
+			return nil, tooManyAttemptsError(wait)
+		}
+	}
+
+	wait, locked, locks := s.limiter.reserve(emailKey(email), s.emailPolicy(), now)
+	if locked || wait > 0 {
+		// A tentativa não acontece: devolve a vaga reservada no IP
+		if ip != "" {
+			s.limiter.release(ipKey(ip), s.ipPolicy())
+		}
+		if locked {
+			return nil, accountLockedError(wait)
+		}
+		return nil, tooManyAttemptsError(wait)
+	}
+	return &attempt{email: email, ip: ip, locks: locks}, nil
+}
+
+// Login correto: esquece as falhas do email e devolve a tentativa ao IP
+func (s *Store) attemptSucceeded(a *attempt) {
+	s.limiter.reset(emailKey(a.email))
+	if a.ip != "" {
+		s.limiter.release(ipKey(a.ip), s.ipPolicy())
+	}
+}
+
+// Login errado: a falha já foi contada; se ela bloqueou a conta, envia o link de desbloqueio
+func (s *Store) attemptFailed(ctx context.Context, a *attempt) {
+	if !a.locks {
+		return
+	}
+	if err := s.sendUnlockEmail(ctx, a.email); err != nil {
+		log.Printf("Erro ao enviar email de desbloqueio para %s: %v", a.email, err)
+	}
+}
+
+// Envia o link que desbloqueia a conta antes do fim do bloqueio
+func (s *Store) sendUnlockEmail(ctx context.Context, email string) error {
+	s.mu.Lock()
+	user, exists := s.users[email]
+	if !exists {
+		s.mu.Unlock()
+		return nil
+	}
+
+	token, err := s.issueActionToken(user.ID, purposeUnlockAccount, s.opts.LockoutDuration)
+	if err == nil {
+		err = s.persist()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	link := s.opts.AppURL + "/desbloquear-conta?token=" + url.QueryEscape(token)
+	return s.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "CineBase - conta bloqueada temporariamente",
+		Body: fmt.Sprintf("Olá, %s!\n\nSua conta foi bloqueada após várias tentativas de login com senha errada. "+
+			"Se foi você, desbloqueie pelo link abaixo:\n\n%s\n\n"+
+			"Se não foi você, recomendamos redefinir sua senha.\n",
+			user.Name, link),
+	})
+}
+
+// Erro para links de desbloqueio inválidos, expirados ou já usados
+var ErrInvalidUnlockToken = errors.New("link de desbloqueio inválido ou expirado")
+
+// Desbloqueia a conta usando o token do email de bloqueio
+func (s *Store) UnlockAccount(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.consumeActionToken(token, purposeUnlockAccount)
+	if !ok {
+		return ErrInvalidUnlockToken
+	}
+	user := s.findByID(t.UserID)
+	if user == nil {
+		return ErrInvalidUnlockToken
+	}
+
+	if err := s.persist(); err != nil {
+		s.actionTokens[t.Hash] = t
+		return err
+	}
+	s.limiter.reset(emailKey(user.Email))
+	return nil
+}
+
+// Segundos até a próxima tentativa, arredondados para cima
+func retryAfter(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+// Conta bloqueada temporariamente
+func accountLockedError(wait time.Duration) error {
+	return &CodedError{
+		Code:    "ACCOUNT_LOCKED",
+		Message: "conta bloqueada temporariamente por excesso de tentativas",
+		Details: map[string]interface{}{"retryAfter": retryAfter(wait)},
+	}
+}
+
+// Espera progressiva entre tentativas
+func tooManyAttemptsError(wait time.Duration) error {
+	return &CodedError{
+		Code:    "TOO_MANY_ATTEMPTS",
+		Message: "muitas tentativas; aguarde antes de tentar novamente",
+		Details: map[string]interface{}{"retryAfter": retryAfter(wait)},
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware lê o header "Authorization: Bearer <token>" e coloca o usuário no context.
+// O token pode ser um JWT ou uma chave de API (também aceita no header X-API-Key).
+// No modo cookie, requisições sem esses headers usam o access token do cookie de sessão.
+// Requisições sem token seguem adiante como anônimas; cabe a cada campo exigir login.
+func Middleware(store *Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		client := ClientFromRequest(c)
+		ctx := WithClient(c.UserContext(), client)
+
+		header := c.Get(fiber.HeaderAuthorization)
+		token, found := strings.CutPrefix(header, "Bearer ")
+		token = strings.TrimSpace(token)
+		if apiKey := strings.TrimSpace(c.Get("X-API-Key")); apiKey != "" {
+			token, found = apiKey, true
+		}
+		headerAuth := found && token != ""
+
+		var cookies *cookieState
+		if store.CookiesEnabled() {
+			cookies = readCookies(c, headerAuth)
+			ctx = context.WithValue(ctx, cookieKey{}, cookies)
+			if !headerAuth {
+				token = strings.Clone(c.Cookies(SessionCookie))
+			}
+		}
+
+		c.SetUserContext(store.authenticate(ctx, token, client))
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		// Os resolvers de login, refresh e logout pedem a troca dos cookies
+		if cookies != nil {
+			return store.writeCookies(c, cookies)
+		}
+		return nil
+	}
+}
+
+// Valida o token e retorna o context com o usuário ou com o motivo da falha
+func (s *Store) authenticate(ctx context.Context, token string, client ClientInfo) context.Context {
+	if token == "" {
+		return withAuthError(ctx, ErrMissingToken)
+	}
+
+	if IsAPIKey(token) {
+		user, key, err := s.AuthenticateAPIKey(token)
+		if err != nil {
+			return withAuthError(ctx, err)
+		}
+		return withAPIKey(ctx, user, key)
+	}
+
+	claims, err := ParseToken(token)
+	if err != nil {
+		return withAuthError(ctx, err)
+	}
+
+	// O usuário pode ter sido removido depois da emissão do token
+	user, err := s.GetByID(claims.Subject)
+	if err != nil {
+		return withAuthError(ctx, ErrInvalidToken)
+	}
+
+	// Tokens de sessões encerradas (logout) são recusados mesmo antes de expirar
+	if !s.touchSession(user.ID, claims.SessionID, client) {
+		return withAuthError(ctx, ErrTokenRevoked)
+	}
+
+	return WithUser(ctx, user, claims.SessionID)
+}
+
+// Dados do cliente de uma requisição HTTP. Os valores do Fiber apontam para buffers
+// reaproveitados entre requisições, por isso são copiados (ficam guardados na sessão).
+func ClientFromRequest(c *fiber.Ctx) ClientInfo {
+	return ClientInfo{
+		IP:        strings.Clone(c.IP()), // Considera PROXY_HEADER em conexões vindas de TRUSTED_PROXIES
+		UserAgent: strings.Clone(c.Get(fiber.HeaderUserAgent)),
+	}
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"movies-api/internal/model"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algoritmos de hash de senha suportados
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+// Tamanhos do salt e do hash gerados pelo argon2id
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// Limites aceitos nos parâmetros do argon2id, tanto na política quanto nos hashes
+// gravados: fora deles o IDKey entra em pânico (p=0) ou aloca memória sem limite
+// a cada tentativa de login
+const (
+	argon2MaxTime    = 64
+	argon2MaxMemory  = 1024 * 1024 // KiB (1 GiB)
+	argon2MaxThreads = 64
+	argon2MinSaltLen = 8
+	argon2MaxSaltLen = 64
+	argon2MinKeyLen  = 16
+	argon2MaxKeyLen  = 128
+)
+
+// Política de hash de senha; campos zerados usam os valores padrão.
+// Hashes gravados carregam os próprios parâmetros, então mudar a política não
+// invalida senhas antigas: elas são refeitas no próximo login bem-sucedido.
+type PasswordHashing struct {
+	Algorithm     string // argon2id (padrão) ou bcrypt
+	BcryptCost    int    // Custo do bcrypt (padrão 12)
+	Argon2Time    uint32 // Iterações do argon2id (padrão 2)
+	Argon2Memory  uint32 // Memória do argon2id em KiB (padrão 19456, 19 MiB)
+	Argon2Threads uint8  // Paralelismo do argon2id (padrão 1)
+}
+
+// Preenche os campos não informados com os valores padrão
+func (p PasswordHashing) withDefaults() PasswordHashing {
+	if p.Algorithm == "" {
+		p.Algorithm = HashArgon2id
+	}
+	if p.BcryptCost == 0 {
+		p.BcryptCost = 12
+	}
+	if p.Argon2Time == 0 {
+		p.Argon2Time = 2
+	}
+	if p.Argon2Memory == 0 {
+		p.Argon2Memory = 19 * 1024
+	}
+	if p.Argon2Threads == 0 {
+		p.Argon2Threads = 1
+	}
+	return p
+}
+
+// Recusa configurações que gerariam hashes inválidos
+func (p PasswordHashing) validate() error {
+	switch p.Algorithm {
+	case HashArgon2id, HashBcrypt:
+	default:
+		return fmt.Errorf("algoritmo de hash de senha desconhecido: %q", p.Algorithm)
+	}
+	if p.BcryptCost < bcrypt.MinCost || p.BcryptCost > bcrypt.MaxCost {
+		return fmt.Errorf("custo do bcrypt deve ficar entre %d e %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return argon2Params{time: p.Argon2Time, memory: p.Argon2Memory, threads: p.Argon2Threads}.validate()
+}
+
+// Gera o hash da senha com a política atual
+func (p PasswordHashing) hash(password string) (string, error) {
+	if p.Algorithm == HashBcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	}
+
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Argon2Time, p.Argon2Memory, p.Argon2Threads, argon2KeyLen)
+
+	// Formato PHC, o mesmo da implementação de referência
+	b64 := base64.RawStdEncoding.EncodeToString
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Argon2Memory, p.Argon2Time, p.Argon2Threads, b64(salt), b64(key)), nil
+}
+
+// Indica se o hash foi gerado com outro algoritmo ou outros parâmetros
+func (p PasswordHashing) needsRehash(encoded string) bool {
+	if p.Algorithm == HashBcrypt {
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != p.BcryptCost
+	}
+
+	params, _, key, err := parseArgon2(encoded)
+	return err != nil ||
+		params.time != p.Argon2Time ||
+		params.memory != p.Argon2Memory ||
+		params.threads != p.Argon2Threads ||
+		len(key) != argon2KeyLen
+}
+
+// Confere a senha com um hash de qualquer algoritmo suportado
+func verifyPassword(encoded, password string) bool {
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		params, salt, key, err := parseArgon2(encoded)
+		if err != nil {
+			return false
+		}
+		got := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(got, key) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+}
+
+// Parâmetros lidos de um hash argon2id
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// Recusa parâmetros fora dos limites aceitos (a memória mínima do argon2 é 8 KiB por thread)
+func (p argon2Params) validate() error {
+	switch {
+	case p.time < 1 || p.time > argon2MaxTime:
+		return fmt.Errorf("iterações do argon2id devem ficar entre 1 e %d", argon2MaxTime)
+	case p.threads < 1 || p.threads > argon2MaxThreads:
+		return fmt.Errorf("paralelismo do argon2id deve ficar entre 1 e %d", argon2MaxThreads)
+	case p.memory < 8*uint32(p.threads) || p.memory > argon2MaxMemory:
+		return fmt.Errorf("memória do argon2id deve ficar entre %d e %d KiB", 8*uint32(p.threads), argon2MaxMemory)
+	}
+	return nil
+}
+
+// Lê um hash no formato $argon2id$v=19$m=...,t=...,p=...$salt$hash
+func parseArgon2(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errors.New("hash argon2id inválido")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("versão do argon2id não suportada")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, errors.New("parâmetros do argon2id inválidos")
+	}
+	// O hash vem do arquivo de usuários: parâmetros absurdos não podem chegar ao IDKey
+	if err := params.validate(); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	if len(salt) < argon2MinSaltLen || len(salt) > argon2MaxSaltLen {
+		return params, nil, nil, errors.New("salt do argon2id com tamanho inválido")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	if len(key) < argon2MinKeyLen || len(key) > argon2MaxKeyLen {
+		return params, nil, nil, errors.New("hash do argon2id com tamanho inválido")
+	}
+	return params, salt, key, nil
+}
+
+// Gera o hash da senha com a política configurada no Store
+func (s *Store) hashPassword(password string) (string, error) {
+	return s.opts.PasswordHashing.hash(password)
+}
+
+// Compara a senha com o hash do usuário (contas sem senha nunca conferem)
+func checkPassword(user *model.User, password string) bool {
+	if user.Password == "" {
+		return false
+	}
+	return verifyPassword(user.Password, password)
+}
+
+// Refaz o hash com a política atual depois de um login com senha correta.
+// Falhas só são registradas: o login já foi validado e o hash antigo continua valendo.
+func (s *Store) upgradePasswordHash(user *model.User, password string) {
+	if !s.opts.PasswordHashing.needsRehash(user.Password) {
+		return
+	}
+
+	// O hash é lento; gera antes de pegar o lock
+	hashed, err := s.hashPassword(password)
+	if err != nil {
+		log.Printf("Erro ao atualizar hash da senha de %s: %v", user.Email, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A senha pode ter mudado entre a verificação e agora
+	current, exists := s.users[user.Email]
+	if !exists || current.Password != user.Password {
+		return
+	}
+
+	updated := *current
+	updated.Password = hashed
+	s.users[user.Email] = &updated
+	if err := s.persist(); err != nil {
+		s.users[user.Email] = current
+		log.Printf("Erro ao atualizar hash da senha de %s: %v", user.Email, err)
+	}
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Regras para senhas escolhidas pelo usuário; campos zerados usam os valores padrão
+type PasswordPolicy struct {
+	MinLength int // Mínimo de caracteres (padrão 8)
+	MaxLength int // Máximo de caracteres (padrão 128)
+
+	// Arquivo com senhas comuns proibidas, uma por linha, somado à lista embutida
+	DenylistFile string
+
+	// Pasta com hashes SHA-1 de senhas vazadas no formato k-anonymity do Have I Been Pwned:
+	// um arquivo por prefixo de 5 caracteres (ex: 21BD1.txt) com linhas "SUFIXO:CONTAGEM"
+	BreachedDir string
+}
+
+// Preenche os campos não informados com os valores padrão
+func (p PasswordPolicy) withDefaults() PasswordPolicy {
+	if p.MinLength <= 0 {
+		p.MinLength = 8
+	}
+	if p.MaxLength <= 0 {
+		p.MaxLength = 128
+	}
+	return p
+}
+
+// Senhas mais comuns em vazamentos, proibidas mesmo sem arquivo configurado
+var commonPasswords = []string{
+	"123456", "12345678", "123456789", "1234567890", "12345", "1234567", "111111", "000000",
+	"123123", "654321", "password", "password1", "password123", "qwerty", "qwerty123",
+	"abc123", "iloveyou", "admin", "admin123", "welcome", "letmein", "monkey", "dragon",
+	"football", "baseball", "sunshine", "princess", "senha", "senha123", "senha1234",
+	"mudar123", "brasil", "flamengo", "corinthians", "palmeiras", "cinebase",
+}
+
+// O bcrypt só aceita senhas de até 72 bytes (letras acentuadas ocupam mais de um)
+const bcryptMaxBytes = 72
+
+// Política já carregada (lista de senhas proibidas em memória)
+type passwordChecker struct {
+	policy   PasswordPolicy
+	denylist map[string]bool
+	maxBytes int // Limite em bytes imposto pelo algoritmo de hash (0 = sem limite)
+}
+
+// Carrega a lista de senhas proibidas e confere se a pasta de vazamentos existe;
+// o algoritmo de hash pode limitar o tamanho da senha além de MaxLength
+func newPasswordChecker(policy PasswordPolicy, hashing PasswordHashing) (*passwordChecker, error) {
+	if policy.MinLength > policy.MaxLength {
+		return nil, errors.New("tamanho mínimo da senha maior que o máximo")
+	}
+
+	c := &passwordChecker{policy: policy, denylist: make(map[string]bool)}
+	if hashing.Algorithm == HashBcrypt {
+		c.maxBytes = bcryptMaxBytes
+	}
+	for _, p := range commonPasswords {
+		c.denylist[p] = true
+	}
+
+	if policy.DenylistFile != "" {
+		f, err := os.Open(policy.DenylistFile)
+		if err != nil {
+			return nil, fmt.Errorf("lista de senhas proibidas: %w", err)
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				c.denylist[strings.ToLower(line)] = true
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("lista de senhas proibidas: %w", err)
+		}
+	}
+
+	if policy.BreachedDir != "" {
+		if info, err := os.Stat(policy.BreachedDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("pasta de senhas vazadas %s não encontrada", policy.BreachedDir)
+		}
+	}
+	return c, nil
+}
+
+// Confere a senha contra a política; nil quando aceita
+func (c *passwordChecker) check(field, password string) *FieldError {
+	length := utf8.RuneCountInString(password)
+	switch {
+	case length < c.policy.MinLength:
+		return &FieldError{Field: field, Code: "TOO_SHORT",
+			Message: fmt.Sprintf("a senha precisa ter ao menos %d caracteres", c.policy.MinLength)}
+	case length > c.policy.MaxLength:
+		return &FieldError{Field: field, Code: "TOO_LONG",
+			Message: fmt.Sprintf("a senha pode ter no máximo %d caracteres", c.policy.MaxLength)}
+	case c.maxBytes > 0 && len(password) > c.maxBytes:
+		return &FieldError{Field: field, Code: "TOO_LONG",
+			Message: fmt.Sprintf("a senha pode ter no máximo %d bytes (letras acentuadas contam mais de um)", c.maxBytes)}
+	case c.denylist[strings.ToLower(password)]:
+		return &FieldError{Field: field, Code: "TOO_COMMON",
+			Message: "essa senha é muito comum; escolha outra"}
+	case c.breached(password):
+		return &FieldError{Field: field, Code: "BREACHED",
+			Message: "essa senha apareceu em vazamentos de dados; escolha outra"}
+	}
+	return nil
+}
+
+// Procura o hash da senha no arquivo do prefixo; só o prefixo decide qual arquivo
+// é lido, como na consulta k-anonymity. Erros de leitura não bloqueiam o cadastro.
+func (c *passwordChecker) breached(password string) bool {
+	if c.policy.BreachedDir == "" {
+		return false
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(c.policy.BreachedDir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Erro ao consultar senhas vazadas: %v", err)
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(line), suffix) {
+			return true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Erro ao consultar senhas vazadas: %v", err)
+	}
+	return false
+}
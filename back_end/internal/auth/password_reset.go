@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"movies-api/internal/mail"
+	"movies-api/internal/model"
+)
+
+// Erro para tokens de redefinição inválidos, expirados ou já usados
+var ErrInvalidResetToken = errors.New("link de redefinição inválido ou expirado")
+
+// Envia um link de redefinição de senha para o email, se ele estiver cadastrado.
+// Emails desconhecidos são ignorados em silêncio para não revelar quem tem conta.
+func (s *Store) RequestPasswordReset(ctx context.Context, email string) error {
+	s.mu.Lock()
+	user, exists := s.users[canonicalEmail(email)]
+	if !exists {
+		s.mu.Unlock()
+		return nil
+	}
+
+	token, err := s.issueActionToken(user.ID, purposePasswordReset, s.opts.PasswordResetTTL)
+	if err == nil {
+		err = s.persist()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	link := s.opts.AppURL + "/redefinir-senha?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "CineBase - redefinição de senha",
+		Body: fmt.Sprintf("Olá, %s!\n\nRecebemos um pedido para redefinir sua senha. "+
+			"Use o link abaixo em até %d minutos:\n\n%s\n\nSe não foi você, ignore este email.\n",
+			user.Name, int(s.opts.PasswordResetTTL.Minutes()), link),
+	}
+
+	// Falha de envio fica só no log, para a resposta ser igual à de um email desconhecido
+	if err := s.sendMail(ctx, msg); err != nil {
+		log.Printf("Erro ao enviar email de redefinição para %s: %v", user.Email, err)
+	}
+	return nil
+}
+
+// Troca a senha usando o token recebido por email, encerra todas as sessões
+// do usuário e desbloqueia a conta
+func (s *Store) ResetPassword(token, newPassword string) error {
+	if err := validationError(s.passwords.check("newPassword", newPassword)); err != nil {
+		return err
+	}
+	hashed, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.consumeActionToken(token, purposePasswordReset)
+	if !ok {
+		return ErrInvalidResetToken
+	}
+
+	user := s.findByID(t.UserID)
+	if user == nil {
+		return ErrInvalidResetToken
+	}
+
+	updated := *user
+	updated.Password = hashed
+	s.users[user.Email] = &updated
+
+	now := time.Now()
+	var revoked []*model.Session
+	for _, session := range s.sessions {
+		if session.UserID == user.ID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			revoked = append(revoked, session)
+		}
+	}
+
+	if err := s.persist(); err != nil {
+		// Desfaz tudo para o usuário poder tentar de novo com o mesmo link
+		s.users[user.Email] = user
+		s.actionTokens[t.Hash] = t
+		for _, session := range revoked {
+			session.RevokedAt = nil
+		}
+		return err
+	}
+
+	// Quem provou ser dono do email também sai de um eventual bloqueio
+	s.limiter.reset(emailKey(user.Email))
+	return nil
+}
+
+// Entrega um email pelo Mailer configurado
+func (s *Store) sendMail(ctx context.Context, msg mail.Message) error {
+	if s.opts.Mailer == nil {
+		return errors.New("mailer não configurado")
+	}
+	return s.opts.Mailer.Send(ctx, msg)
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Store em memória com o algoritmo de hash informado (custos baixos para o teste ser rápido)
+func newHashingTestStore(t *testing.T, algorithm string) *Store {
+	t.Helper()
+	s, err := NewStore(NewMemoryBackend(), Options{
+		PasswordHashing: PasswordHashing{
+			Algorithm:    algorithm,
+			BcryptCost:   4,
+			Argon2Time:   1,
+			Argon2Memory: 64,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Código do primeiro campo de um erro VALIDATION_FAILED
+func fieldCode(err error) string {
+	var coded *CodedError
+	if !errors.As(err, &coded) || coded.Code != "VALIDATION_FAILED" {
+		return ""
+	}
+	fields, _ := coded.Details["fields"].([]map[string]interface{})
+	if len(fields) == 0 {
+		return ""
+	}
+	code, _ := fields[0]["code"].(string)
+	return code
+}
+
+func TestLongPasswordsPerAlgorithm(t *testing.T) {
+	ascii100 := strings.Repeat("x7Kq", 25) // 100 bytes, 100 caracteres
+	accented := strings.Repeat("çãõé", 10) // 80 bytes, 40 caracteres
+	exact72 := strings.Repeat("Zp3m", 18)  // 72 bytes
+
+	tests := []struct {
+		algorithm string
+		password  string
+		tooLong   bool
+	}{
+		{HashArgon2id, ascii100, false},
+		{HashArgon2id, accented, false},
+		{HashArgon2id, exact72, false},
+		{HashBcrypt, ascii100, true},
+		{HashBcrypt, accented, true},
+		{HashBcrypt, exact72, false},
+	}
+	for i, tt := range tests {
+		s := newHashingTestStore(t, tt.algorithm)
+		email := "longa" + string(rune('a'+i)) + "@example.com"
+
+		_, err := s.Signup("Teste", email, tt.password, "")
+		if tt.tooLong {
+			if code := fieldCode(err); code != "TOO_LONG" {
+				t.Errorf("%s com %d bytes: erro %v, esperado TOO_LONG", tt.algorithm, len(tt.password), err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s com %d bytes: %v", tt.algorithm, len(tt.password), err)
+			continue
+		}
+		if _, err := s.Login(context.Background(), email, tt.password); err != nil {
+			t.Errorf("%s com %d bytes: login falhou: %v", tt.algorithm, len(tt.password), err)
+		}
+	}
+}
+
+func TestChangePasswordTooLongForBcrypt(t *testing.T) {
+	s := newHashingTestStore(t, HashBcrypt)
+	user, err := s.Signup("Teste", "troca@example.com", "senha-inicial-ok", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.ChangePassword(user.ID, "", "senha-inicial-ok", strings.Repeat("ñ", 40))
+	if code := fieldCode(err); code != "TOO_LONG" {
+		t.Errorf("ChangePassword com 80 bytes: erro %v, esperado TOO_LONG", err)
+	}
+}
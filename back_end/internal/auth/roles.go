@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"movies-api/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// Papéis que podem ser atribuídos
+var knownRoles = map[string]bool{
+	model.RoleUser:  true,
+	model.RoleAdmin: true,
+}
+
+// Garante que exista um admin com o email informado: cria a conta (já verificada)
+// ou promove a conta existente, sem alterar a senha dela
+func (s *Store) EnsureAdmin(name, email, password string) error {
+	email, invalid := normalizeEmail("email", email)
+	if invalid != nil {
+		return errors.New("email do admin inicial inválido")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, exists := s.users[email]; exists {
+		if user.HasRole(model.RoleAdmin) {
+			return nil
+		}
+		updated := *user
+		updated.Roles = append(append([]string{}, user.Roles...), model.RoleAdmin)
+		s.users[email] = &updated
+		if err := s.persist(); err != nil {
+			s.users[email] = user
+			return err
+		}
+		return nil
+	}
+
+	if password == "" {
+		return errors.New("senha do admin inicial não definida")
+	}
+	// Mesma política do cadastro: a conta mais poderosa não pode ter a senha mais fraca
+	if invalid := s.passwords.check("password", password); invalid != nil {
+		return fmt.Errorf("senha do admin inicial recusada: %s", invalid.Message)
+	}
+	hashed, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	s.users[email] = &model.User{
+		ID:            uuid.New().String(),
+		Name:          name,
+		Email:         email,
+		Password:      hashed,
+		EmailVerified: true,
+		Roles:         []string{model.RoleUser, model.RoleAdmin},
+	}
+	if err := s.persist(); err != nil {
+		delete(s.users, email)
+		return err
+	}
+	return nil
+}
+
+// Lista todos os usuários, em ordem de email
+func (s *Store) ListUsers() []*model.User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*model.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sortUsers(users)
+	return users
+}
+
+// Recusa tirar o papel de admin do único admin, ou excluir a conta dele, que deixaria
+// o sistema sem administração
+var ErrLastAdmin = &CodedError{
+	Code:    "LAST_ADMIN",
+	Message: "o sistema precisa de pelo menos um admin",
+}
+
+// Substitui os papéis do usuário; o papel user é sempre mantido
+func (s *Store) SetRoles(userID string, roles []string) (*model.User, error) {
+	normalized := []string{model.RoleUser}
+	for _, r := range roles {
+		if !knownRoles[r] {
+			return nil, fmt.Errorf("papel desconhecido: %s", r)
+		}
+		if !slices.Contains(normalized, r) {
+			normalized = append(normalized, r)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findByID(userID)
+	if user == nil {
+		return nil, errors.New("usuário não encontrado")
+	}
+	if user.HasRole(model.RoleAdmin) && !slices.Contains(normalized, model.RoleAdmin) && s.countAdmins() == 1 {
+		return nil, ErrLastAdmin
+	}
+
+	updated := *user
+	updated.Roles = normalized
+	s.users[user.Email] = &updated
+	if err := s.persist(); err != nil {
+		s.users[user.Email] = user
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Conta os usuários com papel de admin (chamar com o lock já adquirido)
+func (s *Store) countAdmins() int {
+	n := 0
+	for _, u := range s.users {
+		if u.HasRole(model.RoleAdmin) {
+			n++
+		}
+	}
+	return n
+}
+
+// Remove o bloqueio e a espera de login de um email (uso administrativo)
+func (s *Store) UnlockUser(email string) {
+	s.limiter.reset(emailKey(canonicalEmail(email)))
+}
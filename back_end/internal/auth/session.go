@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"movies-api/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// Par de tokens entregue no login e em cada refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time // Expiração do access token
+
+	RefreshExpiresAt time.Time // Expiração da sessão (e do refresh token)
+}
+
+// Erro devolvido para refresh tokens inválidos, expirados ou já usados
+var ErrInvalidRefreshToken = errors.New("refresh token inválido")
+
+// Erro para sessões inexistentes ou de outro usuário
+var ErrSessionNotFound = errors.New("sessão não encontrada")
+
+// Intervalo mínimo entre gravações do último acesso de uma sessão
+const sessionTouchInterval = time.Minute
+
+// Abre uma nova sessão para o usuário e emite o primeiro par de tokens;
+// o dispositivo (IP e user agent) vem do context da requisição
+func (s *Store) StartSession(ctx context.Context, user *model.User) (*TokenPair, error) {
+	client := ClientFromContext(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneSessions(now)
+
+	session := &model.Session{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(s.opts.RefreshTokenTTL),
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		LastSeenAt: now,
+	}
+
+	pair, err := s.rotate(session, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.sessions[session.ID] = session
+	if err := s.persist(); err != nil {
+		delete(s.sessions, session.ID)
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Troca um refresh token válido por um novo par; o token antigo deixa de valer.
+// Reapresentar um refresh token já trocado indica vazamento e encerra a sessão inteira.
+func (s *Store) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	sessionID, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	session, exists := s.sessions[sessionID]
+	if !exists || !session.Active(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(session.RefreshHash)) != 1 {
+		// Token de uma rotação anterior: alguém mais tem uma cópia
+		session.RevokedAt = &now
+		if err := s.persist(); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	previous := *session
+	pair, err := s.rotate(session, now)
+	if err != nil {
+		return nil, err
+	}
+	seen(session, ClientFromContext(ctx), now)
+	if err := s.persist(); err != nil {
+		*session = previous
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Encerra uma sessão; o refresh token e os access tokens dela deixam de valer
+func (s *Store) Logout(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	session.RevokedAt = &now
+	return s.persist()
+}
+
+// Encerra todas as sessões do usuário
+func (s *Store) LogoutAll(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	return s.persist()
+}
+
+// Lista as sessões ativas do usuário, da usada mais recentemente para a mais antiga
+func (s *Store) ListSessions(userID string) []*model.Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var list []*model.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.Active(now) {
+			copied := s.withActivity(session)
+			list = append(list, &copied)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeenAt.After(list[j].LastSeenAt)
+	})
+	return list
+}
+
+// Encerra uma sessão do próprio usuário (ex: dispositivo que ele não reconhece)
+func (s *Store) RevokeSession(userID, sessionID string) error {
+	s.mu.RLock()
+	session, exists := s.sessions[sessionID]
+	owned := exists && session.UserID == userID
+	s.mu.RUnlock()
+
+	// Sessões de outros usuários respondem como inexistentes
+	if !owned {
+		return ErrSessionNotFound
+	}
+	return s.Logout(sessionID)
+}
+
+// Último acesso de uma sessão ainda não aplicado ao model.Session (ver touchSession)
+type sessionActivity struct {
+	at     time.Time
+	client ClientInfo
+}
+
+// Confere se a sessão existe, pertence ao usuário e não foi encerrada, registrando o acesso.
+// Roda em toda requisição autenticada, então só pega o lock de leitura do Store: o acesso
+// fica em s.activity e vai para a sessão (e para o arquivo) no máximo uma vez por
+// sessionTouchInterval.
+func (s *Store) touchSession(userID, sessionID string, client ClientInfo) bool {
+	now := time.Now()
+
+	s.mu.RLock()
+	session, exists := s.sessions[sessionID]
+	valid := exists && session.UserID == userID && session.Active(now)
+	due := valid && now.Sub(session.LastSeenAt) >= sessionTouchInterval
+	s.mu.RUnlock()
+	if !valid {
+		return false
+	}
+
+	s.activityMu.Lock()
+	previous := s.activity[sessionID].client
+	if client.IP == "" {
+		client.IP = previous.IP
+	}
+	if client.UserAgent == "" {
+		client.UserAgent = previous.UserAgent
+	}
+	s.activity[sessionID] = sessionActivity{at: now, client: client}
+	s.activityMu.Unlock()
+
+	if due {
+		s.flushActivity(sessionID)
+	}
+	return true
+}
+
+// Aplica à sessão o último acesso registrado e grava o arquivo
+func (s *Store) flushActivity(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	s.activityMu.Lock()
+	act, pending := s.activity[sessionID]
+	// Outra requisição pode ter gravado enquanto esta esperava o lock
+	apply := exists && pending && act.at.Sub(session.LastSeenAt) >= sessionTouchInterval
+	if apply {
+		delete(s.activity, sessionID)
+	}
+	s.activityMu.Unlock()
+	if !apply {
+		return
+	}
+
+	seen(session, act.client, act.at)
+	// Falha aqui só atrasa o registro do acesso; não deve derrubar a requisição
+	if err := s.persist(); err != nil {
+		log.Printf("Erro ao registrar acesso da sessão %s: %v", sessionID, err)
+	}
+}
+
+// Retorna a sessão com o último acesso ainda não gravado, se houver (chamar com o lock já adquirido)
+func (s *Store) withActivity(session *model.Session) model.Session {
+	copied := *session
+	s.activityMu.Lock()
+	act, pending := s.activity[session.ID]
+	s.activityMu.Unlock()
+	if pending && act.at.After(copied.LastSeenAt) {
+		seen(&copied, act.client, act.at)
+	}
+	return copied
+}
+
+// Atualiza o último acesso e o dispositivo da sessão
+func seen(session *model.Session, client ClientInfo, now time.Time) {
+	session.LastSeenAt = now
+	if client.IP != "" {
+		session.IP = client.IP
+	}
+	if client.UserAgent != "" {
+		session.UserAgent = client.UserAgent
+	}
+}
+
+// Gera um novo refresh token para a sessão e um access token vinculado a ela
+// (chamar com o lock de escrita já adquirido)
+func (s *Store) rotate(session *model.Session, now time.Time) (*TokenPair, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := GenerateToken(session.UserID, session.ID, s.opts.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	session.RefreshHash = hashSecret(secret)
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: session.ID + "." + secret,
+		ExpiresAt:    now.Add(s.opts.AccessTokenTTL),
+
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Remove sessões cujo refresh token já expirou (chamar com o lock de escrita)
+func (s *Store) pruneSessions(now time.Time) {
+	for id, session := range s.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+
+	s.activityMu.Lock()
+	defer s.activityMu.Unlock()
+	for id := range s.activity {
+		if _, exists := s.sessions[id]; !exists {
+			delete(s.activity, id)
+		}
+	}
+}
+
+// Gera um segredo aleatório de 256 bits codificado para URL
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Segredos são guardados apenas como SHA-256
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
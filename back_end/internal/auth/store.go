@@ -1,40 +1,223 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"movies-api/internal/mail"
 	"movies-api/internal/model"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// Configurações do Store; campos zerados usam os valores padrão
+type Options struct {
+	AccessTokenTTL  time.Duration // Validade do access token (padrão 15min)
+	RefreshTokenTTL time.Duration // Validade da sessão/refresh token (padrão 30 dias)
+
+	PasswordResetTTL time.Duration // Validade do link de redefinição de senha (padrão 1h)
+	Mailer           mail.Mailer   // Entrega dos emails de conta
+	AppURL           string        // Endereço do front end usado nos links dos emails
+
+	VerificationTTL      time.Duration // Validade do link de confirmação de email (padrão 48h)
+	RequireVerifiedEmail bool          // Recusa login de contas com email não confirmado
+
+	LockoutThreshold int           // Falhas de login seguidas até bloquear a conta (padrão 10)
+	LockoutDuration  time.Duration // Duração do bloqueio da conta (padrão 15min)
+
+	PasswordHashing PasswordHashing // Algoritmo e custo dos hashes de senha (padrão argon2id)
+	PasswordPolicy  PasswordPolicy  // Regras para senhas novas (tamanho, senhas comuns e vazadas)
+
+	Cookies CookieOptions // Sessão por cookie HttpOnly para o front end web (desativada por padrão)
+
+	Registration string // Modo de cadastro: open (padrão), invite ou closed
+}
+
+// Preenche os campos não informados com os valores padrão
+func (o Options) withDefaults() Options {
+	if o.AccessTokenTTL <= 0 {
+		o.AccessTokenTTL = 15 * time.Minute
+	}
+	if o.RefreshTokenTTL <= 0 {
+		o.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	if o.PasswordResetTTL <= 0 {
+		o.PasswordResetTTL = time.Hour
+	}
+	if o.VerificationTTL <= 0 {
+		o.VerificationTTL = 48 * time.Hour
+	}
+	if o.LockoutThreshold <= 0 {
+		o.LockoutThreshold = 10
+	}
+	if o.LockoutDuration <= 0 {
+		o.LockoutDuration = 15 * time.Minute
+	}
+	o.PasswordHashing = o.PasswordHashing.withDefaults()
+	o.PasswordPolicy = o.PasswordPolicy.withDefaults()
+	o.Cookies = o.Cookies.withDefaults()
+	if o.Registration == "" {
+		o.Registration = RegistrationOpen
+	}
+	o.AppURL = strings.TrimSuffix(o.AppURL, "/")
+	return o
+}
+
 // Store com mutex para garantir acesso seguro em concorrência
 type Store struct {
-	mu    sync.RWMutex
-	users map[string]*model.User
+	mu       sync.RWMutex
+	users    map[string]*model.User
+	sessions map[string]*model.Session // Sessões por ID
+	backend  Backend                   // Onde os dados são persistidos
+	opts     Options
+
+	actionTokens map[string]*model.ActionToken // Tokens de uso único por hash
+	limiter      *Limiter                      // Falhas de login (só em memória)
+	identities   map[string]*model.Identity    // Vínculos com provedores externos por issuer|subject
+	apiKeys      map[string]*model.APIKey      // Chaves de API por ID
+	passwords    *passwordChecker              // Política de senhas já carregada
+	invites      map[string]*model.Invite      // Convites de cadastro por ID
+
+	activityMu     sync.Mutex                 // Protege activity e apiKeyActivity sem passar pelo lock de escrita do Store
+	activity       map[string]sessionActivity // Último acesso por sessão, ainda não gravado
+	apiKeyActivity map[string]time.Time       // Último uso por chave de API, ainda não gravado
 }
 
-// Cria e retorna uma nova instância de Store
-func NewStore() *Store {
-	return &Store{
-		users: make(map[string]*model.User), // Inicializa o mapa de usuários
+// Cria um Store carregando os dados já salvos no backend
+func NewStore(backend Backend, opts Options) (*Store, error) {
+	opts = opts.withDefaults()
+	if err := opts.PasswordHashing.validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.Cookies.validate(); err != nil {
+		return nil, err
 	}
+	if err := validRegistrationMode(opts.Registration); err != nil {
+		return nil, err
+	}
+	passwords, err := newPasswordChecker(opts.PasswordPolicy, opts.PasswordHashing)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := backend.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		users:    make(map[string]*model.User), // Inicializa o mapa de usuários
+		sessions: make(map[string]*model.Session),
+		backend:  backend,
+		opts:     opts,
+
+		actionTokens: make(map[string]*model.ActionToken),
+		limiter:      NewLimiter(),
+		identities:   make(map[string]*model.Identity),
+		apiKeys:      make(map[string]*model.APIKey),
+		passwords:    passwords,
+		invites:      make(map[string]*model.Invite),
+		activity:     make(map[string]sessionActivity),
+
+		apiKeyActivity: make(map[string]time.Time),
+	}
+	for _, u := range snap.Users {
+		s.users[u.Email] = u
+	}
+	for _, sess := range snap.Sessions {
+		s.sessions[sess.ID] = sess
+	}
+	for _, t := range snap.ActionTokens {
+		s.actionTokens[t.Hash] = t
+	}
+	for _, id := range snap.Identities {
+		s.identities[identityKeyOf(id.Issuer, id.Subject)] = id
+	}
+	for _, k := range snap.APIKeys {
+		s.apiKeys[k.ID] = k
+	}
+	for _, inv := range snap.Invites {
+		s.invites[inv.ID] = inv
+	}
+	return s, nil
 }
 
-// Cadastra um novo usuário senha criptografada
-func (s *Store) Signup(name, email, password string) (*model.User, error) {
+// Grava o estado atual no backend (chamar com o lock de escrita já adquirido)
+func (s *Store) persist() error {
+	snap := &Snapshot{}
+	for _, u := range s.users {
+		copied := *u
+		snap.Users = append(snap.Users, &copied)
+	}
+	for _, sess := range s.sessions {
+		copied := *sess
+		snap.Sessions = append(snap.Sessions, &copied)
+	}
+	for _, t := range s.actionTokens {
+		copied := *t
+		snap.ActionTokens = append(snap.ActionTokens, &copied)
+	}
+	for _, id := range s.identities {
+		copied := *id
+		snap.Identities = append(snap.Identities, &copied)
+	}
+	for _, k := range s.apiKeys {
+		copied := *k
+		snap.APIKeys = append(snap.APIKeys, &copied)
+	}
+	for _, inv := range s.invites {
+		copied := *inv
+		snap.Invites = append(snap.Invites, &copied)
+	}
+	// Ordem estável para que o arquivo não mude à toa entre gravações
+	sortUsers(snap.Users)
+	sort.Slice(snap.Sessions, func(i, j int) bool {
+		return snap.Sessions[i].ID < snap.Sessions[j].ID
+	})
+	sort.Slice(snap.ActionTokens, func(i, j int) bool {
+		return snap.ActionTokens[i].Hash < snap.ActionTokens[j].Hash
+	})
+	sort.Slice(snap.Identities, func(i, j int) bool {
+		return identityKeyOf(snap.Identities[i].Issuer, snap.Identities[i].Subject) <
+			identityKeyOf(snap.Identities[j].Issuer, snap.Identities[j].Subject)
+	})
+	sort.Slice(snap.APIKeys, func(i, j int) bool {
+		return snap.APIKeys[i].ID < snap.APIKeys[j].ID
+	})
+	sort.Slice(snap.Invites, func(i, j int) bool {
+		return snap.Invites[i].ID < snap.Invites[j].ID
+	})
+	return s.backend.Save(snap)
+}
+
+// Cadastra um novo usuário senha criptografada. Conforme o modo de cadastro,
+// exige um código de convite, que é consumido junto com a criação da conta.
+func (s *Store) Signup(name, email, password, inviteCode string) (*model.User, error) {
+	// Valida email e senha juntos, para o front end mostrar todos os problemas de uma vez
+	email, emailErr := normalizeEmail("email", email)
+	if err := validationError(emailErr, s.passwords.check("password", password)); err != nil {
+		return nil, err
+	}
+
+	// Criptografa a senha com a política configurada; o hash é lento, então vem antes do lock
+	hashed, err := s.hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()         // Trava para escrita
 	defer s.mu.Unlock() // Libera o lock no fim da função
 
 	// Verifica se o email já está cadastrado
 	if _, exists := s.users[email]; exists {
-		return nil, errors.New("email já cadastrado")
+		return nil, errEmailTaken("email")
 	}
 
-	// Criptografa a senha usando bcrypt
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	undoInvite, err := s.admitSignup(inviteCode)
 	if err != nil {
 		return nil, err
 	}
@@ -44,36 +227,93 @@ func (s *Store) Signup(name, email, password string) (*model.User, error) {
 		ID:       uuid.New().String(),
 		Name:     name,
 		Email:    email,
-		Password: string(hashed), // Salva senha criptografada
+		Password: hashed, // Salva senha criptografada
+		Roles:    []string{model.RoleUser},
 	}
 
 	s.users[email] = user
+	if err := s.persist(); err != nil {
+		delete(s.users, email) // Desfaz o cadastro se não conseguiu salvar
+		undoInvite()
+		return nil, err
+	}
 	return user, nil
 }
 
-// Realiza login verificando email e senha
-func (s *Store) Login(email, password string) (*model.User, error) {
+// Realiza login verificando email e senha, com limite de tentativas por email e por IP
+func (s *Store) Login(ctx context.Context, email, password string) (*model.User, error) {
+	email = canonicalEmail(email)
+	ip := ClientFromContext(ctx).IP
+	now := time.Now()
+
+	// Bloqueios valem antes de olhar a senha: é isso que impede a adivinhação
+	attempt, err := s.beginAttempt(email, ip, now)
+	if err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock() // Lock para leitura
 	user, exists := s.users[email]
 	s.mu.RUnlock() // Desbloqueia leitura
 
 	if !exists {
+		s.attemptFailed(ctx, attempt)
 		return nil, errors.New("usuário não encontrado")
 	}
 
 	// Compara senha informada com a hash armazenada
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
+	if !checkPassword(user, password) {
+		s.attemptFailed(ctx, attempt)
 		return nil, errors.New("senha incorreta")
 	}
+	s.attemptSucceeded(attempt)
+	s.upgradePasswordHash(user, password)
+
+	// Só depois da senha correta, para não revelar o estado de contas alheias
+	if s.opts.RequireVerifiedEmail && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
 
 	return user, nil
 }
 
+// Busca um usuário pelo email
+func (s *Store) GetByEmail(email string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.users[canonicalEmail(email)]
+	if !exists {
+		return nil, errors.New("usuário não encontrado")
+	}
+	return user, nil
+}
+
+// Busca um usuário pelo ID
+func (s *Store) GetByID(id string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if user := s.findByID(id); user != nil {
+		return user, nil
+	}
+	return nil, errors.New("usuário não encontrado")
+}
+
+// Procura o usuário pelo ID (chamar com o lock já adquirido)
+func (s *Store) findByID(id string) *model.User {
+	for _, user := range s.users {
+		if user.ID == id {
+			return user
+		}
+	}
+	return nil
+}
+
 // Método auxiliar que apenas valida email e senha (sem retornar usuário)
 func (s *Store) Authenticate(email, password string) bool {
 	s.mu.RLock()
-	user, exists := s.users[email]
+	user, exists := s.users[canonicalEmail(email)]
 	s.mu.RUnlock()
 
 	if !exists {
@@ -81,6 +321,12 @@ func (s *Store) Authenticate(email, password string) bool {
 	}
 
 	// Retorna true se a senha for válida
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	return err == nil
+	return checkPassword(user, password)
+}
+
+// Ordena usuários por email
+func sortUsers(users []*model.User) {
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Email < users[j].Email
+	})
 }
@@ -1,25 +1,71 @@
 package auth
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-// Chave secreta usada para assinar o token (poderia vir de uma variável de ambiente)
-var jwtKey = []byte("secreta")
+// Erros retornados na validação de tokens
+var (
+	ErrMissingToken = errors.New("token de autenticação ausente")
+	ErrTokenExpired = errors.New("token expirado")
+	ErrInvalidToken = errors.New("token inválido")
+	ErrTokenRevoked = errors.New("sessão encerrada")
+)
+
+// Claims do access token: "sub" é o ID do usuário e "sid" a sessão que o emitiu
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid"`
+}
 
-// Gera um token JWT com o email como "subject"
-func GenerateToken(email string) (string, error) {
-	claims := &jwt.RegisteredClaims{
-		Subject:   email,                                              // Define o "sub" (usuário) do token
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Expira em 24h
-		IssuedAt:  jwt.NewNumericDate(time.Now()),                     // Marca a data de emissão
+// Gera um access token JWT para o usuário, vinculado a uma sessão
+func GenerateToken(userID, sessionID string, ttl time.Duration) (string, error) {
+	ks, err := currentKeySet()
+	if err != nil {
+		return "", err
 	}
+	key := ks.signingKey()
 
-	// Cria um token com algoritmo HS256 e os dados (claims) definidos
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	now := time.Now()
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),              // "jti" único por token
+			Subject:   userID,                           // Define o "sub" (usuário) do token
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)), // Access tokens têm vida curta
+			IssuedAt:  jwt.NewNumericDate(now),          // Marca a data de emissão
+		},
+		SessionID: sessionID,
+	}
+
+	// Cria o token com o algoritmo da chave ativa e identifica a chave no header
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
 
-	// Assina o token com a chave secreta e retorna como string
-	return token.SignedString(jwtKey)
+	// Assina o token com a chave ativa e retorna como string
+	return token.SignedString(key.sign)
+}
+
+// Valida assinatura e expiração do token e retorna suas claims
+func ParseToken(tokenString string) (*Claims, error) {
+	ks, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	// A chave é escolhida pelo "kid", permitindo várias chaves válidas durante a rotação
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, ks.keyFunc,
+		jwt.WithValidMethods(ks.methods()), jwt.WithExpirationRequired())
+
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, ErrTokenExpired
+	case err != nil:
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
 }
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Parâmetros TOTP (RFC 6238) suportados por todos os apps autenticadores
+const (
+	totpPeriod = 30 // Segundos por passo
+	totpDigits = 6
+	totpSkew   = 1 // Passos aceitos antes e depois do atual, para relógios dessincronizados
+)
+
+// Nome exibido no app autenticador
+const totpIssuer = "CineBase"
+
+// Codificação base32 sem padding usada nos segredos
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Gera um segredo aleatório de 160 bits em base32
+func newTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// Calcula o código do passo informado (HOTP da RFC 4226 com HMAC-SHA1)
+func totpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Truncamento dinâmico
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// Passo TOTP de um instante
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// Confere o código contra a janela de passos em volta de agora, ignorando passos
+// já usados; retorna o passo aceito
+func verifyTOTP(secret, code string, lastStep int64, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totpCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// URI otpauth:// lida pelos apps autenticadores (normalmente exibida como QR code)
+func totpURI(secret, account string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", totpIssuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(totpIssuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
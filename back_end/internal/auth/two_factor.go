@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"movies-api/internal/model"
+)
+
+// Finalidade do token que liga o login com senha à etapa do segundo fator
+const purposeTwoFactorLogin = "two_factor_login"
+
+// Validade do desafio entre a senha e o código
+const twoFactorChallengeTTL = 5 * time.Minute
+
+// Quantidade de códigos de recuperação gerados na ativação
+const recoveryCodeCount = 10
+
+// Erros do segundo fator
+var (
+	ErrInvalidTwoFactorCode      = errors.New("código de verificação inválido")
+	ErrInvalidTwoFactorChallenge = errors.New("desafio de login inválido ou expirado; entre novamente com a senha")
+	ErrTwoFactorEnabled          = errors.New("autenticação em dois fatores já está ativada")
+	ErrTwoFactorNotEnabled       = errors.New("autenticação em dois fatores não está ativada")
+	ErrTwoFactorNotStarted       = errors.New("inicie a ativação da autenticação em dois fatores primeiro")
+)
+
+// Dados para cadastrar a conta no app autenticador
+type TwoFactorSetup struct {
+	Secret string // Segredo base32, para digitar manualmente
+	URI    string // otpauth://, para o QR code
+}
+
+// Gera um segredo pendente; o segundo fator só passa a valer depois de ConfirmTwoFactor.
+// Contas sem senha (login externo) não confirmam senha.
+func (s *Store) BeginTwoFactorSetup(userID, password string) (*TwoFactorSetup, error) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findByID(userID)
+	if user == nil {
+		return nil, errors.New("usuário não encontrado")
+	}
+	if user.Password != "" && !checkPassword(user, password) {
+		return nil, ErrWrongPassword
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	updated := *user
+	updated.TOTPSecret = secret
+	updated.TOTPLastStep = 0
+	s.users[user.Email] = &updated
+	if err := s.persist(); err != nil {
+		s.users[user.Email] = user
+		return nil, err
+	}
+	return &TwoFactorSetup{Secret: secret, URI: totpURI(secret, user.Email)}, nil
+}
+
+// Ativa o segundo fator com o primeiro código do app e retorna os códigos de
+// recuperação (mostrados uma única vez; só os hashes ficam salvos)
+func (s *Store) ConfirmTwoFactor(userID, code string) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findByID(userID)
+	if user == nil {
+		return nil, errors.New("usuário não encontrado")
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotStarted
+	}
+	step, ok := verifyTOTP(user.TOTPSecret, strings.TrimSpace(code), user.TOTPLastStep, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	updated := *user
+	updated.TwoFactorEnabled = true
+	updated.TOTPLastStep = step
+	updated.RecoveryCodes = hashes
+	s.users[user.Email] = &updated
+	if err := s.persist(); err != nil {
+		s.users[user.Email] = user
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Desativa o segundo fator exigindo a senha (se houver) e um código válido
+func (s *Store) DisableTwoFactor(userID, password, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findByID(userID)
+	if user == nil {
+		return errors.New("usuário não encontrado")
+	}
+	if user.Password != "" && !checkPassword(user, password) {
+		return ErrWrongPassword
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if _, ok := checkTwoFactorCode(user, code, time.Now()); !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	updated := *user
+	updated.TwoFactorEnabled = false
+	updated.TOTPSecret = ""
+	updated.TOTPLastStep = 0
+	updated.RecoveryCodes = nil
+	s.users[user.Email] = &updated
+	if err := s.persist(); err != nil {
+		s.users[user.Email] = user
+		return err
+	}
+	return nil
+}
+
+// Cria o desafio que o cliente troca, junto com o código, pelos tokens da sessão
+func (s *Store) StartTwoFactorChallenge(user *model.User) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.issueActionToken(user.ID, purposeTwoFactorLogin, twoFactorChallengeTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.persist(); err != nil {
+		delete(s.actionTokens, hashSecret(token))
+		return "", err
+	}
+	return token, nil
+}
+
+// Conclui o login conferindo o código TOTP ou de recuperação. Códigos errados contam
+// como falhas de login do email (mesma espera e bloqueio); o desafio continua válido
+// até expirar para o usuário poder tentar de novo.
+func (s *Store) VerifyTwoFactor(ctx context.Context, challenge, code string) (*model.User, error) {
+	ip := ClientFromContext(ctx).IP
+	now := time.Now()
+
+	s.mu.Lock()
+	t, ok := s.consumeActionToken(challenge, purposeTwoFactorLogin)
+	var user *model.User
+	if ok {
+		user = s.findByID(t.UserID)
+	}
+	if user == nil || !user.TwoFactorEnabled {
+		s.mu.Unlock()
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	restore := func() { s.actionTokens[t.Hash] = t }
+
+	attempt, err := s.beginAttempt(user.Email, ip, now)
+	if err != nil {
+		restore()
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	updated, ok := checkTwoFactorCode(user, code, now)
+	if !ok {
+		restore()
+		s.mu.Unlock()
+		// Fora do lock: o envio do email de bloqueio precisa dele
+		s.attemptFailed(ctx, attempt)
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	s.users[user.Email] = updated
+	if err := s.persist(); err != nil {
+		s.users[user.Email] = user
+		restore()
+		s.mu.Unlock()
+		s.attemptSucceeded(attempt) // O código estava certo; só a gravação falhou
+		return nil, err
+	}
+	s.mu.Unlock()
+
+	s.attemptSucceeded(attempt)
+	return updated, nil
+}
+
+// Confere um código TOTP ou de recuperação; retorna a cópia do usuário com o passo
+// registrado ou o código de recuperação removido (nenhum dos dois vale duas vezes)
+func checkTwoFactorCode(user *model.User, code string, now time.Time) (*model.User, bool) {
+	code = strings.TrimSpace(code)
+	updated := *user
+
+	if step, ok := verifyTOTP(user.TOTPSecret, code, user.TOTPLastStep, now); ok {
+		updated.TOTPLastStep = step
+		return &updated, true
+	}
+
+	hash := hashSecret(normalizeRecoveryCode(code))
+	for i, h := range user.RecoveryCodes {
+		if h == hash {
+			updated.RecoveryCodes = append(append([]string{}, user.RecoveryCodes[:i]...), user.RecoveryCodes[i+1:]...)
+			return &updated, true
+		}
+	}
+	return nil, false
+}
+
+// Gera os códigos de recuperação no formato xxxxx-xxxxx e seus hashes
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(totpEncoding.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashSecret(raw))
+	}
+	return codes, hashes, nil
+}
+
+// Aceita o código com ou sem hífen, em maiúsculas ou minúsculas
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
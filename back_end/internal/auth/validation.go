@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// Problema em um campo específico da entrada
+type FieldError struct {
+	Field   string // Nome do argumento GraphQL (ex: "password")
+	Code    string // Código estável para o front end (ex: "TOO_SHORT")
+	Message string
+}
+
+// Agrupa os problemas dos campos em um erro VALIDATION_FAILED; no GraphQL os campos
+// vão em extensions.fields como [{field, code, message}]
+func validationError(fields ...*FieldError) error {
+	list := make([]map[string]interface{}, 0, len(fields))
+	for _, f := range fields {
+		if f == nil {
+			continue
+		}
+		list = append(list, map[string]interface{}{
+			"field":   f.Field,
+			"code":    f.Code,
+			"message": f.Message,
+		})
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return &CodedError{
+		Code:    "VALIDATION_FAILED",
+		Message: list[0]["message"].(string), // Mensagem do primeiro problema, para clientes que só leem "message"
+		Details: map[string]interface{}{"fields": list},
+	}
+}
+
+// Tamanho máximo de um endereço de email (RFC 5321)
+const maxEmailLength = 254
+
+// Valida o endereço (RFC 5322, sem nome de exibição) e o normaliza para minúsculas,
+// para que Foo@X.com e foo@x.com sejam a mesma conta
+func normalizeEmail(field, email string) (string, *FieldError) {
+	email = strings.TrimSpace(email)
+	invalid := &FieldError{Field: field, Code: "INVALID_EMAIL", Message: "email inválido"}
+	if email == "" || len(email) > maxEmailLength {
+		return "", invalid
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", invalid
+	}
+
+	// Exige um domínio com ponto (ex: recusa "foo@localhost")
+	_, domain, _ := strings.Cut(email, "@")
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", invalid
+	}
+	return strings.ToLower(email), nil
+}
+
+// Forma canônica usada nas buscas; não valida, só iguala maiúsculas e espaços
+func canonicalEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Email já usado por outra conta
+func errEmailTaken(field string) error {
+	return validationError(&FieldError{Field: field, Code: "TAKEN", Message: "email já cadastrado"})
+}
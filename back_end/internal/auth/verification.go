@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"movies-api/internal/mail"
+	"movies-api/internal/model"
+)
+
+// Erro para links de verificação inválidos, expirados ou já usados
+var ErrInvalidVerificationToken = errors.New("link de verificação inválido ou expirado")
+
+// Envia um novo link de confirmação para o email, se a conta existir e ainda não estiver verificada
+func (s *Store) SendVerificationEmail(ctx context.Context, email string) error {
+	s.mu.Lock()
+	user, exists := s.users[canonicalEmail(email)]
+	if !exists || user.EmailVerified {
+		s.mu.Unlock()
+		return nil
+	}
+
+	token, err := s.issueActionToken(user.ID, purposeVerifyEmail, s.opts.VerificationTTL)
+	if err == nil {
+		err = s.persist()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	link := s.opts.AppURL + "/verificar-email?token=" + url.QueryEscape(token)
+	return s.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "CineBase - confirme seu email",
+		Body: fmt.Sprintf("Olá, %s!\n\nConfirme seu email abrindo o link abaixo em até %d horas:\n\n%s\n",
+			user.Name, int(s.opts.VerificationTTL.Hours()), link),
+	})
+}
+
+// Confirma o email usando o token recebido: marca a conta como verificada ou,
+// se o link veio de uma troca de email, passa a usar o novo endereço
+func (s *Store) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	s.mu.Lock()
+
+	t, ok := s.consumeActionToken(token, purposeVerifyEmail)
+	if !ok {
+		t, ok = s.consumeActionToken(token, purposeChangeEmail)
+	}
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrInvalidVerificationToken
+	}
+
+	user := s.findByID(t.UserID)
+	if user == nil {
+		s.mu.Unlock()
+		return nil, ErrInvalidVerificationToken
+	}
+
+	var updated *model.User
+	if t.Purpose == purposeChangeEmail {
+		var err error
+		if updated, err = s.applyEmailChange(user, t.Data); err != nil {
+			// O link continua valendo: o endereço pode ser liberado antes de expirar
+			s.actionTokens[t.Hash] = t
+			s.mu.Unlock()
+			return nil, err
+		}
+	} else {
+		copied := *user
+		copied.EmailVerified = true
+		updated = &copied
+		s.users[user.Email] = updated
+	}
+
+	if err := s.persist(); err != nil {
+		delete(s.users, updated.Email)
+		s.users[user.Email] = user
+		s.actionTokens[t.Hash] = t
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.mu.Unlock()
+
+	if updated.Email != user.Email {
+		s.notifyEmailChanged(ctx, user.Email, updated)
+	}
+	return updated, nil
+}
@@ -1,53 +1,272 @@
 package cache
 
 import (
+	"container/list"
+	"log"
 	"movies-api/internal/model"
+	"strings"
 	"sync"
 	"time"
 )
 
-// Estrutura para armazenar o filme e o tempo de expiração
-type cacheItem struct {
-	Movie     *model.Movie
-	ExpiresAt time.Time
+// Cache guarda valores do tipo V por chave, para evitar buscas e cálculos repetidos.
+// Cada instância é um namespace com TTL próprio (ex: filmes por ID, listas calculadas).
+type Cache[K ~string, V any] interface {
+	// Get retorna o valor guardado; found = false se não existe ou passou de MaxAge
+	Get(key K) (entry Entry[V], found bool)
+	// Set guarda o valor: fresco até o TTL, disponível como velho até MaxAge
+	Set(key K, value V)
+	// Delete remove o valor; retorna se ele estava guardado
+	Delete(key K) (bool, error)
+	// DeletePrefix remove os valores cuja chave começa com prefix ("" remove todos); retorna quantos saíram
+	DeletePrefix(prefix string) (int, error)
+	// Keys lista as chaves que começam com prefix ("" lista todas)
+	Keys(prefix string) ([]K, error)
+	// Len retorna quantos valores estão guardados
+	Len() (int, error)
+	// Close libera os recursos do cache (goroutines, conexões)
+	Close()
 }
 
-// Cache com controle de concorrência e TTL
-type Cache struct {
-	mu    sync.RWMutex
-	items map[string]cacheItem
-	ttl   time.Duration
+// Filmes da OMDb por ID
+type MovieCache = Cache[string, *model.Movie]
+
+// Listas de filmes já calculadas (rankings, filtros), por nome da lista
+type ListCache = Cache[string, []*model.Movie]
+
+// Falhas definitivas ao buscar filmes (cache negativo), por ID
+type FailureCache = Cache[string, Failure]
+
+// Valor lido do cache
+type Entry[V any] struct {
+	Value V
+	Stale bool // Passou do TTL: ainda pode ser servido, mas deve ser atualizado
 }
 
-// Cria uma nova instância de cache com TTL definido
-func NewCache(ttl time.Duration) *Cache {
-	return &Cache{
-		items: make(map[string]cacheItem),
-		ttl:   ttl,
+// Configurações do cache em memória; campos zerados usam os valores padrão
+type Options struct {
+	TTL             time.Duration // Tempo em que o valor é considerado fresco (padrão 6h)
+	MaxAge          time.Duration // Tempo máximo servindo o valor velho enquanto a origem não responde (padrão 7 dias)
+	MaxEntries      int           // Máximo de valores guardados; o menos usado recentemente sai primeiro (padrão 10000)
+	CleanupInterval time.Duration // Intervalo da limpeza de itens expirados (padrão 10min)
+
+	SnapshotFile     string        // Arquivo onde o cache é salvo no Close e recarregado na criação (vazio desativa)
+	SnapshotInterval time.Duration // Intervalo entre snapshots com o servidor rodando (padrão 15min)
+}
+
+// Preenche os campos não informados com os valores padrão
+func (o Options) withDefaults() Options {
+	if o.TTL <= 0 {
+		o.TTL = 6 * time.Hour
+	}
+	if o.MaxAge <= 0 {
+		o.MaxAge = 7 * 24 * time.Hour
+	}
+	if o.MaxAge < o.TTL {
+		o.MaxAge = o.TTL
+	}
+	if o.MaxEntries <= 0 {
+		o.MaxEntries = 10000
 	}
+	if o.CleanupInterval <= 0 {
+		o.CleanupInterval = 10 * time.Minute
+	}
+	if o.SnapshotInterval <= 0 {
+		o.SnapshotInterval = 15 * time.Minute
+	}
+	return o
+}
+
+// Estrutura para armazenar o valor e os tempos de expiração
+type cacheItem[K ~string, V any] struct {
+	Key        K
+	Value      V
+	FreshUntil time.Time // Depois disso o valor é velho (Stale)
+	ExpiresAt  time.Time // Depois disso o valor some do cache
 }
 
-// Tenta recuperar um filme do cache
-func (c *Cache) Get(id string) (*model.Movie, bool) {
-	c.mu.RLock() // Lock para leitura
-	defer c.mu.RUnlock()
+// MemoryCache guarda os valores no próprio processo, com TTL e limite de tamanho (LRU)
+type MemoryCache[K ~string, V any] struct {
+	mu    sync.Mutex          // Get também altera a ordem de uso, então não há lock só de leitura
+	items map[K]*list.Element // Elementos de order por chave
+	order *list.List          // Do usado mais recentemente (frente) ao menos recente (fundo)
+	opts  Options
+
+	stop      chan struct{} // Fechado por Close para encerrar a limpeza
+	done      chan struct{} // Fechado quando a limpeza terminou
+	closeOnce sync.Once
+}
+
+// Cria o cache em memória, recarrega o snapshot (se configurado) e inicia a
+// limpeza periódica de itens expirados (encerrada por Close)
+func NewMemoryCache[K ~string, V any](opts Options) *MemoryCache[K, V] {
+	c := &MemoryCache[K, V]{
+		items: make(map[K]*list.Element),
+		order: list.New(),
+		opts:  opts.withDefaults(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if c.opts.SnapshotFile != "" {
+		// O cache só evita requisições: um snapshot ilegível não impede o servidor de subir
+		if n, err := c.loadSnapshot(); err != nil {
+			log.Printf("Erro ao carregar o snapshot do cache: %v", err)
+		} else if n > 0 {
+			log.Printf("Cache restaurado de %s com %d itens", c.opts.SnapshotFile, n)
+		}
+	}
+	go c.janitor()
+	return c
+}
+
+// Tenta recuperar um valor do cache
+func (c *MemoryCache[K, V]) Get(key K) (Entry[V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.items[key] // Busca item pela chave
+	if !found {
+		return Entry[V]{}, false
+	}
+	item := el.Value.(*cacheItem[K, V])
+	now := time.Now()
+	if now.After(item.ExpiresAt) {
+		// Expirado: remove já, sem esperar a limpeza
+		c.remove(el)
+		return Entry[V]{}, false
+	}
+
+	c.order.MoveToFront(el) // Marca como usado agora
+	return Entry[V]{Value: item.Value, Stale: now.After(item.FreshUntil)}, true
+}
+
+// Armazena um valor no cache (fresco por TTL, expira após MaxAge),
+// descartando os menos usados se passar do limite
+func (c *MemoryCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	item := &cacheItem[K, V]{Key: key, Value: value, FreshUntil: now.Add(c.opts.TTL), ExpiresAt: now.Add(c.opts.MaxAge)}
+	if el, found := c.items[key]; found {
+		el.Value = item
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(item)
+	for c.order.Len() > c.opts.MaxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// Remove um valor do cache
+func (c *MemoryCache[K, V]) Delete(key K) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.items[key]
+	if found {
+		c.remove(el)
+	}
+	return found, nil
+}
+
+// Remove os valores cuja chave começa com prefix ("" esvazia o cache)
+func (c *MemoryCache[K, V]) DeletePrefix(prefix string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, el := range c.items {
+		if strings.HasPrefix(string(key), prefix) {
+			c.remove(el)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Lista as chaves que começam com prefix, inclusive as expiradas ainda não limpas
+func (c *MemoryCache[K, V]) Keys(prefix string) ([]K, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var keys []K
+	for key := range c.items {
+		if strings.HasPrefix(string(key), prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Quantidade de valores guardados (inclui expirados ainda não limpos)
+func (c *MemoryCache[K, V]) Len() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len(), nil
+}
+
+// Encerra a limpeza periódica e grava o snapshot final; pode ser chamado mais de uma vez
+func (c *MemoryCache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		<-c.done
+		if c.opts.SnapshotFile != "" {
+			if err := c.saveSnapshot(); err != nil {
+				log.Printf("Erro ao salvar o snapshot do cache: %v", err)
+			}
+		}
+	})
+}
+
+// Remove um elemento (chamar com o lock adquirido)
+func (c *MemoryCache[K, V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*cacheItem[K, V]).Key)
+}
+
+// Remove os itens expirados a cada CleanupInterval e, com SnapshotFile,
+// salva o cache a cada SnapshotInterval, até Close
+func (c *MemoryCache[K, V]) janitor() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.opts.CleanupInterval)
+	defer ticker.Stop()
+
+	var snapshots <-chan time.Time // Nil (nunca dispara) sem SnapshotFile
+	if c.opts.SnapshotFile != "" {
+		snapshotTicker := time.NewTicker(c.opts.SnapshotInterval)
+		defer snapshotTicker.Stop()
+		snapshots = snapshotTicker.C
+	}
 
-	item, found := c.items[id] // Busca item pelo ID
-	if !found || time.Now().After(item.ExpiresAt) {
-		// Se não encontrado ou expirado, retorna false
-		return nil, false
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.deleteExpired()
+		case <-snapshots:
+			if err := c.saveSnapshot(); err != nil {
+				log.Printf("Erro ao salvar o snapshot do cache: %v", err)
+			}
+		}
 	}
-	return item.Movie, true // Retorna filme e sucesso = true
 }
 
-// Armazena um filme no cache com expiração baseada no TTL
-func (c *Cache) Set(id string, movie *model.Movie) {
+// Percorre o cache removendo os itens vencidos
+func (c *MemoryCache[K, V]) deleteExpired() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Define o novo item com expiração futura
-	c.items[id] = cacheItem{
-		Movie:     movie,
-		ExpiresAt: time.Now().Add(c.ttl), // Expira após o TTL definido
+	now := time.Now()
+	for el := c.order.Back(); el != nil; {
+		prev := el.Prev()
+		if now.After(el.Value.(*cacheItem[K, V]).ExpiresAt) {
+			c.remove(el)
+		}
+		el = prev
 	}
 }
@@ -0,0 +1,322 @@
+package cache
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Depois de uma atualização em segundo plano falhar, espera isto antes de tentar de novo
+const refreshRetryDelay = time.Minute
+
+// Busca um valor na origem (ex: filme na OMDb) quando ele não está no cache
+type FetchFunc[K ~string, V any] func(key K) (V, error)
+
+// Loader lê valores do cache e, na falta, da origem. Buscas simultâneas da mesma
+// chave compartilham uma única requisição à origem e o mesmo resultado ou erro.
+// Valores velhos (Stale) são servidos na hora e atualizados em segundo plano.
+type Loader[K ~string, V any] struct {
+	cache    Cache[K, V]
+	fetch    FetchFunc[K, V]
+	onChange func(key K) // Chamada depois que um valor diferente do guardado é gravado
+
+	failures  Cache[K, Failure] // Cache negativo: chaves cuja busca falhou de forma definitiva
+	permanent func(error) bool  // Diz se o erro da origem é definitivo (ex: ID inexistente)
+
+	mu    sync.Mutex
+	calls map[K]*call[V]  // Buscas em andamento por chave
+	retry map[K]time.Time // Quando a atualização que falhou pode ser tentada de novo
+
+	storeMu sync.Mutex    // Serializa gravar resultados com Invalidate e ForceReload
+	gen     atomic.Uint64 // Muda a cada Invalidate; buscas iniciadas antes não gravam
+
+	hits, staleHits, misses, coalesced, fetches, fetchErrors, negativeHits atomic.Int64
+}
+
+// Falha definitiva guardada no cache negativo
+type Failure struct {
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Contadores do Loader desde a criação
+type Stats struct {
+	Hits         int64 // Valores frescos servidos do cache
+	StaleHits    int64 // Valores velhos servidos do cache (com atualização em segundo plano)
+	Misses       int64 // Valores ausentes do cache
+	Coalesced    int64 // Misses que aproveitaram uma busca já em andamento
+	Fetches      int64 // Requisições feitas à origem
+	FetchErrors  int64 // Requisições à origem que falharam
+	NegativeHits int64 // Falhas definitivas respondidas pelo cache negativo, sem ir à origem
+}
+
+// Retorna os contadores atuais
+func (l *Loader[K, V]) Stats() Stats {
+	return Stats{
+		Hits:         l.hits.Load(),
+		StaleHits:    l.staleHits.Load(),
+		Misses:       l.misses.Load(),
+		Coalesced:    l.coalesced.Load(),
+		Fetches:      l.fetches.Load(),
+		FetchErrors:  l.fetchErrors.Load(),
+		NegativeHits: l.negativeHits.Load(),
+	}
+}
+
+// Busca em andamento; done é fechado quando value/err estão prontos
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+
+	gen        uint64 // Geração do Loader quando a busca começou
+	superseded bool   // Uma recarga forçada começou depois: o resultado não é gravado (protegido por storeMu)
+}
+
+// Cria um Loader que guarda no cache o que busca na origem
+func NewLoader[K ~string, V any](c Cache[K, V], fetch FetchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		cache: c,
+		fetch: fetch,
+		calls: make(map[K]*call[V]),
+		retry: make(map[K]time.Time),
+	}
+}
+
+// Registra uma função chamada quando um valor buscado na origem entra no cache ou
+// substitui um diferente (ex: para invalidar dados calculados a partir dele); buscas
+// que trazem o mesmo valor não a chamam. Chamar antes do primeiro Load.
+func (l *Loader[K, V]) OnChange(fn func(key K)) {
+	l.onChange = fn
+}
+
+// Guarda em c as falhas que permanent considerar definitivas, para que a mesma chave
+// não volte à origem até expirarem; falhas transitórias (rede, cota) não entram.
+// Chamar antes do primeiro Load.
+func (l *Loader[K, V]) CacheFailures(c Cache[K, Failure], permanent func(error) bool) {
+	l.failures, l.permanent = c, permanent
+}
+
+// Lista as chaves no cache negativo com o erro de cada uma
+func (l *Loader[K, V]) Failures() (map[K]Failure, error) {
+	result := make(map[K]Failure)
+	if l.failures == nil {
+		return result, nil
+	}
+	keys, err := l.failures.Keys("")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if entry, found := l.failures.Get(key); found {
+			result[key] = entry.Value
+		}
+	}
+	return result, nil
+}
+
+// Retorna o valor do cache ou o busca na origem (cache → origem → salva)
+func (l *Loader[K, V]) Load(key K) (V, error) {
+	entry, found := l.cache.Get(key)
+	if found {
+		if entry.Stale {
+			l.staleHits.Add(1)
+			l.refresh(key)
+		} else {
+			l.hits.Add(1)
+		}
+		return entry.Value, nil
+	}
+
+	l.misses.Add(1)
+	if l.failures != nil {
+		if entry, failed := l.failures.Get(key); failed {
+			l.negativeHits.Add(1)
+			var zero V
+			return zero, errors.New(entry.Value.Error)
+		}
+	}
+	return l.Reload(key)
+}
+
+// Busca o valor na origem mesmo que esteja no cache (positivo ou negativo) e substitui a versão guardada
+func (l *Loader[K, V]) Reload(key K) (V, error) {
+	c, leader := l.begin(key)
+	if !leader {
+		// Outra requisição já está buscando esta chave: espera o resultado dela
+		l.coalesced.Add(1)
+		<-c.done
+		return c.value, c.err
+	}
+	l.run(key, c)
+	return c.value, c.err
+}
+
+// Como Reload, mas sempre com uma busca nova: não aproveita a que já estiver em
+// andamento, que pode ter começado antes da mudança na origem, e impede que ela
+// grave o resultado dela por cima deste
+func (l *Loader[K, V]) ForceReload(key K) (V, error) {
+	c := &call[V]{done: make(chan struct{}), gen: l.gen.Load()}
+	l.mu.Lock()
+	previous, running := l.calls[key]
+	l.calls[key] = c // Quem chegar agora espera por esta busca
+	l.mu.Unlock()
+
+	if running {
+		l.storeMu.Lock()
+		previous.superseded = true
+		l.storeMu.Unlock()
+	}
+	l.run(key, c)
+	return c.value, c.err
+}
+
+// Executa remove (que apaga chaves do cache) de modo que buscas já em andamento não
+// regravem depois os valores apagados: quem espera por elas recebe o resultado,
+// mas ele não é guardado
+func (l *Loader[K, V]) Invalidate(remove func() error) error {
+	l.storeMu.Lock()
+	defer l.storeMu.Unlock()
+	l.gen.Add(1)
+	return remove()
+}
+
+// Atualiza um valor velho em segundo plano, a menos que já esteja sendo
+// buscado ou que a última tentativa tenha falhado há pouco
+func (l *Loader[K, V]) refresh(key K) {
+	l.mu.Lock()
+	retryAt, failed := l.retry[key]
+	l.mu.Unlock()
+	if failed && time.Now().Before(retryAt) {
+		return
+	}
+
+	c, leader := l.begin(key)
+	if !leader {
+		return
+	}
+	go func() {
+		l.run(key, c)
+		if c.err != nil {
+			// Continua servindo o valor velho até MaxAge
+			log.Printf("Erro ao atualizar %s em segundo plano: %v", key, c.err)
+			l.mu.Lock()
+			l.retry[key] = time.Now().Add(refreshRetryDelay)
+			l.mu.Unlock()
+		}
+	}()
+}
+
+// Registra uma busca para a chave; leader = false se já havia uma em andamento
+func (l *Loader[K, V]) begin(key K) (c *call[V], leader bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, running := l.calls[key]; running {
+		return c, false
+	}
+	c = &call[V]{done: make(chan struct{}), gen: l.gen.Load()}
+	l.calls[key] = c
+	return c, true
+}
+
+// Busca na origem, salva no cache e libera quem espera pela busca
+func (l *Loader[K, V]) run(key K, c *call[V]) {
+	l.fetches.Add(1)
+	c.value, c.err = l.fetch(key)
+	if c.err != nil {
+		l.fetchErrors.Add(1)
+	}
+	if l.store(key, c) {
+		l.onChange(key)
+	}
+
+	l.mu.Lock()
+	if l.calls[key] == c { // Uma recarga forçada pode já ter tomado o lugar desta busca
+		delete(l.calls, key)
+	}
+	if c.err == nil {
+		delete(l.retry, key)
+	}
+	l.mu.Unlock()
+	close(c.done)
+}
+
+// Grava o resultado da busca no cache (ou a falha definitiva no cache negativo), a menos
+// que uma invalidação ou recarga forçada tenha vindo depois do início da busca.
+// Retorna se onChange deve ser chamada.
+func (l *Loader[K, V]) store(key K, c *call[V]) (changed bool) {
+	l.storeMu.Lock()
+	defer l.storeMu.Unlock()
+	if c.superseded || c.gen != l.gen.Load() {
+		return false
+	}
+
+	if c.err != nil {
+		if l.failures != nil && l.permanent(c.err) {
+			l.failures.Set(key, Failure{Error: c.err.Error(), FailedAt: time.Now()})
+		}
+		return false
+	}
+
+	changed = true
+	if l.onChange != nil {
+		if previous, found := l.cache.Get(key); found {
+			changed = !reflect.DeepEqual(previous.Value, c.value)
+		}
+	}
+	l.cache.Set(key, c.value)
+	if l.failures != nil {
+		l.failures.Delete(key)
+	}
+	return changed && l.onChange != nil
+}
+
+// Carrega as chaves no cache com no máximo workers buscas simultâneas à origem;
+// retorna quantas falharam. Valores velhos (ex: snapshot antigo) são atualizados
+// pelo próprio worker em vez de em segundo plano, para respeitar o limite.
+func (l *Loader[K, V]) Warm(keys []K, workers int) (failed int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan K)
+	errs := make(chan error, len(keys))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range queue {
+				if err := l.warm(key); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	for _, key := range keys {
+		queue <- key
+	}
+	close(queue)
+	wg.Wait()
+	return len(errs)
+}
+
+// Carrega uma chave no aquecimento; se a atualização de um valor velho falhar,
+// ele continua sendo servido até MaxAge e a chave não conta como falha
+func (l *Loader[K, V]) warm(key K) error {
+	entry, found := l.cache.Get(key)
+	if !found {
+		_, err := l.Load(key)
+		return err
+	}
+	if !entry.Stale {
+		return nil
+	}
+	if _, err := l.Reload(key); err != nil {
+		log.Printf("Erro ao atualizar %s no aquecimento: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configurações da conexão com o Redis; campos zerados usam os valores padrão
+type RedisOptions struct {
+	Addr     string        // host:porta (padrão localhost:6379)
+	Password string        // Vazio desativa o AUTH
+	DB       int           // Banco selecionado com SELECT
+	Prefix   string        // Prefixo de todas as chaves (padrão "cinebase:")
+	PoolSize int           // Conexões ociosas mantidas abertas (padrão 10)
+	Timeout  time.Duration // Limite para conectar e para cada comando (padrão 2s)
+}
+
+// Preenche os campos não informados com os valores padrão
+func (o RedisOptions) withDefaults() RedisOptions {
+	if o.Addr == "" {
+		o.Addr = "localhost:6379"
+	}
+	if o.Prefix == "" {
+		o.Prefix = "cinebase:"
+	}
+	if o.PoolSize <= 0 {
+		o.PoolSize = 10
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 2 * time.Second
+	}
+	return o
+}
+
+// RedisClient mantém o pool de conexões compartilhado pelos caches no Redis
+type RedisClient struct {
+	opts RedisOptions
+	idle chan *redisConn // Conexões prontas para reuso
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Conecta ao Redis e confirma que ele responde
+func NewRedisClient(opts RedisOptions) (*RedisClient, error) {
+	c := &RedisClient{opts: opts.withDefaults()}
+	c.idle = make(chan *redisConn, c.opts.PoolSize)
+
+	if _, err := c.do("PING"); err != nil {
+		return nil, fmt.Errorf("erro ao conectar ao Redis em %s: %w", c.opts.Addr, err)
+	}
+	return c, nil
+}
+
+// Configurações de um namespace no Redis; campos zerados usam os valores padrão
+type RedisCacheOptions struct {
+	Namespace string        // Parte da chave depois do Prefix do cliente, ex: "movie" → cinebase:movie:<id>
+	TTL       time.Duration // Tempo em que o valor é considerado fresco (padrão 6h)
+	MaxAge    time.Duration // Tempo até o Redis apagar o valor; entre TTL e MaxAge ele é servido como velho (padrão 7 dias)
+}
+
+// Preenche os campos não informados com os valores padrão
+func (o RedisCacheOptions) withDefaults() RedisCacheOptions {
+	if o.TTL <= 0 {
+		o.TTL = 6 * time.Hour
+	}
+	if o.MaxAge <= 0 {
+		o.MaxAge = 7 * 24 * time.Hour
+	}
+	if o.MaxAge < o.TTL {
+		o.MaxAge = o.TTL
+	}
+	return o
+}
+
+// Formato gravado no Redis; Value fica cru para distinguir ausente de valor zero
+type redisEntry struct {
+	Value      json.RawMessage `json:"value"`
+	FreshUntil time.Time       `json:"fresh_until"`
+}
+
+// RedisCache guarda valores em JSON num servidor Redis, compartilhado entre instâncias.
+// Falhas do Redis não derrubam as consultas: viram cache miss e são registradas no log.
+type RedisCache[K ~string, V any] struct {
+	client *RedisClient
+	opts   RedisCacheOptions
+	prefix string // Prefix do cliente + namespace
+}
+
+// Cria um namespace no Redis usando o pool do cliente
+func NewRedisCache[K ~string, V any](client *RedisClient, opts RedisCacheOptions) *RedisCache[K, V] {
+	opts = opts.withDefaults()
+	return &RedisCache[K, V]{
+		client: client,
+		opts:   opts,
+		prefix: client.opts.Prefix + opts.Namespace + ":",
+	}
+}
+
+// Tenta recuperar um valor do Redis
+func (c *RedisCache[K, V]) Get(key K) (Entry[V], bool) {
+	reply, err := c.client.do("GET", c.prefix+string(key))
+	if err != nil {
+		log.Printf("Erro ao ler %s do cache: %v", key, err)
+		return Entry[V]{}, false
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return Entry[V]{}, false // Chave inexistente ou expirada
+	}
+
+	var entry redisEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil || len(entry.Value) == 0 {
+		// Inclui chaves gravadas em formatos antigos: viram miss e são regravadas
+		return Entry[V]{}, false
+	}
+	var value V
+	if err := json.Unmarshal(entry.Value, &value); err != nil {
+		log.Printf("Valor inválido para %s no cache: %v", key, err)
+		return Entry[V]{}, false
+	}
+	return Entry[V]{Value: value, Stale: time.Now().After(entry.FreshUntil)}, true
+}
+
+// Armazena um valor no Redis; o próprio Redis o apaga após MaxAge (PX)
+func (c *RedisCache[K, V]) Set(key K, value V) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Erro ao serializar %s para o cache: %v", key, err)
+		return
+	}
+	data, err := json.Marshal(redisEntry{Value: raw, FreshUntil: time.Now().Add(c.opts.TTL)})
+	if err != nil {
+		log.Printf("Erro ao serializar %s para o cache: %v", key, err)
+		return
+	}
+	ttl := strconv.FormatInt(c.opts.MaxAge.Milliseconds(), 10)
+	if _, err := c.client.do("SET", c.prefix+string(key), string(data), "PX", ttl); err != nil {
+		log.Printf("Erro ao gravar %s no cache: %v", key, err)
+	}
+}
+
+// Remove um valor do Redis
+func (c *RedisCache[K, V]) Delete(key K) (bool, error) {
+	reply, err := c.client.do("DEL", c.prefix+string(key))
+	if err != nil {
+		return false, err
+	}
+	n, _ := reply.(int64)
+	return n > 0, nil
+}
+
+// Remove os valores cuja chave começa com prefix ("" remove todo o namespace)
+func (c *RedisCache[K, V]) DeletePrefix(prefix string) (int, error) {
+	removed := 0
+	err := c.client.scan(c.prefix+prefix, func(keys []string) error {
+		reply, err := c.client.do(append([]string{"DEL"}, keys...)...)
+		if err != nil {
+			return err
+		}
+		n, _ := reply.(int64)
+		removed += int(n)
+		return nil
+	})
+	return removed, err
+}
+
+// Lista as chaves do namespace que começam com prefix (via SCAN; uso administrativo)
+func (c *RedisCache[K, V]) Keys(prefix string) ([]K, error) {
+	var keys []K
+	err := c.client.scan(c.prefix+prefix, func(found []string) error {
+		for _, key := range found {
+			keys = append(keys, K(strings.TrimPrefix(key, c.prefix)))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// Conta os valores do namespace (percorre as chaves com SCAN; uso administrativo)
+func (c *RedisCache[K, V]) Len() (int, error) {
+	total := 0
+	err := c.client.scan(c.prefix, func(keys []string) error {
+		total += len(keys)
+		return nil
+	})
+	return total, err
+}
+
+// A conexão pertence ao RedisClient, fechado por quem o criou
+func (c *RedisCache[K, V]) Close() {}
+
+// Percorre as chaves que começam com prefix (já com o Prefix do cliente), em lotes,
+// sem bloquear o Redis como KEYS faria
+func (c *RedisClient) scan(prefix string, fn func(keys []string) error) error {
+	match := globEscape(prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := c.do("SCAN", cursor, "MATCH", match, "COUNT", "500")
+		if err != nil {
+			return err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return errors.New("redis: resposta inesperada ao SCAN")
+		}
+		cursor, _ = parts[0].(string)
+		items, _ := parts[1].([]interface{})
+
+		keys := make([]string, 0, len(items))
+		for _, item := range items {
+			if key, ok := item.(string); ok {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// Escapa os caracteres especiais do padrão MATCH (*, ?, [, ], \)
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Fecha as conexões ociosas; as que estiverem em uso são fechadas ao serem devolvidas
+func (c *RedisClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.idle)
+	for conn := range c.idle {
+		conn.Close()
+	}
+}
+
+// Executa um comando numa conexão do pool
+func (c *RedisClient) do(args ...string) (interface{}, error) {
+	conn, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close() // Erro de rede: o estado da conexão é desconhecido
+		return nil, err
+	}
+	c.release(conn)
+	return reply, err
+}
+
+// Reaproveita uma conexão ociosa ou abre uma nova (autenticada e no banco certo)
+func (c *RedisClient) acquire() (*redisConn, error) {
+	select {
+	case conn, ok := <-c.idle:
+		if ok {
+			return conn, nil
+		}
+		return nil, errors.New("cliente Redis encerrado")
+	default:
+	}
+
+	conn, err := dialRedis(c.opts.Addr, c.opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.Password != "" {
+		if _, err := conn.do("AUTH", c.opts.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := conn.do("SELECT", strconv.Itoa(c.opts.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// Devolve a conexão ao pool ou a fecha se o pool estiver cheio ou encerrado
+func (c *RedisClient) release(conn *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		conn.Close()
+		return
+	}
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
@@ -0,0 +1,369 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Servidor RESP mínimo em processo com os comandos que o RedisCache usa
+type respServer struct {
+	t        *testing.T
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	data     map[string]string
+	expires  map[string]time.Time
+	commands []string // Comandos recebidos, em ordem
+	conns    int      // Conexões aceitas
+}
+
+func newRESPServer(t *testing.T, password string) *respServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &respServer{
+		t:        t,
+		listener: l,
+		password: password,
+		data:     make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	t.Cleanup(func() { l.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *respServer) addr() string { return s.listener.Addr().String() }
+
+func (s *respServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *respServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mu.Unlock()
+
+		var reply string
+		switch {
+		case cmd == "AUTH":
+			if args[1] != s.password {
+				reply = "-WRONGPASS invalid password\r\n"
+			} else {
+				authed = true
+				reply = "+OK\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		default:
+			reply = s.exec(cmd, args[1:])
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// Lê um comando no formato *N\r\n$len\r\narg\r\n...
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("comando inválido %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func bulk(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+func (s *respServer) exec(cmd string, args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, at := range s.expires {
+		if now.After(at) {
+			delete(s.data, key)
+			delete(s.expires, key)
+		}
+	}
+
+	switch cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "SET":
+		s.data[args[0]] = args[1]
+		delete(s.expires, args[0])
+		if len(args) == 4 && strings.ToUpper(args[2]) == "PX" {
+			ms, err := strconv.ParseInt(args[3], 10, 64)
+			if err != nil || ms <= 0 {
+				return "-ERR invalid expire time in 'set' command\r\n"
+			}
+			s.expires[args[0]] = now.Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "GET":
+		value, ok := s.data[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "DEL":
+		removed := 0
+		for _, key := range args {
+			if _, ok := s.data[key]; ok {
+				delete(s.data, key)
+				delete(s.expires, key)
+				removed++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", removed)
+	case "SCAN":
+		// Uma chave por lote para exercitar o cursor. O cursor (opaco para o cliente)
+		// é a última chave devolvida, então apagar chaves no meio da varredura não
+		// pula nenhuma, como no SCAN do Redis.
+		after := strings.TrimPrefix(args[0], "k:")
+		match := "*"
+		for i := 1; i+1 < len(args); i += 2 {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				match = args[i+1]
+			}
+		}
+		keys := make([]string, 0, len(s.data))
+		for key := range s.data {
+			if args[0] == "0" || key > after {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return "*2\r\n" + bulk("0") + "*0\r\n"
+		}
+		sort.Strings(keys)
+		key := keys[0]
+		next := "k:" + key
+		if len(keys) == 1 {
+			next = "0"
+		}
+		if ok, _ := path.Match(match, key); !ok {
+			return "*2\r\n" + bulk(next) + "*0\r\n"
+		}
+		return "*2\r\n" + bulk(next) + "*1\r\n" + bulk(key)
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", cmd)
+}
+
+// Quantas conexões o servidor aceitou
+func (s *respServer) connCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+// Quantos comandos com este nome o servidor recebeu
+func (s *respServer) count(cmd string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, c := range s.commands {
+		if c == cmd {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestRedisClient(t *testing.T, srv *respServer, opts RedisOptions) *RedisClient {
+	t.Helper()
+	opts.Addr = srv.addr()
+	client, err := NewRedisClient(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+type testValue struct {
+	Title string `json:"title"`
+	Year  int    `json:"year"`
+}
+
+func TestRedisCacheGetSet(t *testing.T) {
+	srv := newRESPServer(t, "segredo")
+	client := newTestRedisClient(t, srv, RedisOptions{Password: "segredo", Prefix: "test:"})
+	c := NewRedisCache[string, testValue](client, RedisCacheOptions{Namespace: "movie", TTL: time.Hour, MaxAge: 2 * time.Hour})
+
+	if _, found := c.Get("tt1"); found {
+		t.Fatal("Get antes do Set encontrou valor")
+	}
+
+	c.Set("tt1", testValue{Title: "Alien", Year: 1979})
+	entry, found := c.Get("tt1")
+	if !found || entry.Stale || entry.Value != (testValue{Title: "Alien", Year: 1979}) {
+		t.Fatalf("Get = %+v, %v", entry, found)
+	}
+
+	// A chave leva o prefixo do cliente e o namespace, e expira após MaxAge (PX)
+	srv.mu.Lock()
+	_, stored := srv.data["test:movie:tt1"]
+	expiresIn := time.Until(srv.expires["test:movie:tt1"])
+	srv.mu.Unlock()
+	if !stored {
+		t.Fatal("valor não gravado em test:movie:tt1")
+	}
+	if expiresIn <= time.Hour || expiresIn > 2*time.Hour {
+		t.Errorf("expiração em %v, esperado perto de 2h", expiresIn)
+	}
+
+	// Depois do TTL o valor continua lá, mas marcado como velho
+	stale := NewRedisCache[string, testValue](client, RedisCacheOptions{Namespace: "movie", TTL: time.Millisecond})
+	stale.Set("tt2", testValue{Title: "Heat"})
+	time.Sleep(5 * time.Millisecond)
+	if entry, found := stale.Get("tt2"); !found || !entry.Stale {
+		t.Errorf("Get após o TTL = %+v, %v; esperado valor velho", entry, found)
+	}
+
+	// Valores em formato antigo ou corrompido viram miss
+	srv.mu.Lock()
+	srv.data["test:movie:old"] = `{"title":"formato antigo"}`
+	srv.mu.Unlock()
+	if _, found := c.Get("old"); found {
+		t.Error("valor em formato antigo deveria ser miss")
+	}
+
+	// Todos os comandos usaram a mesma conexão do pool, autenticada uma vez
+	if srv.connCount() != 1 || srv.count("AUTH") != 1 {
+		t.Errorf("conexões = %d, AUTH = %d; esperado 1 e 1", srv.connCount(), srv.count("AUTH"))
+	}
+}
+
+func TestRedisCacheScanAndDelete(t *testing.T) {
+	srv := newRESPServer(t, "")
+	client := newTestRedisClient(t, srv, RedisOptions{Prefix: "test:"})
+	movies := NewRedisCache[string, testValue](client, RedisCacheOptions{Namespace: "movie"})
+	lists := NewRedisCache[string, []string](client, RedisCacheOptions{Namespace: "list"})
+
+	for _, id := range []string{"tt1", "tt2", "tt3", "xx1"} {
+		movies.Set(id, testValue{Title: id})
+	}
+	lists.Set("genre:drama", []string{"tt1"})
+	// Caracteres de glob no prefixo são literais no MATCH
+	movies.Set("a*b", testValue{Title: "glob"})
+
+	if n, err := movies.Len(); err != nil || n != 5 {
+		t.Fatalf("Len = %d, %v; esperado 5", n, err)
+	}
+	keys, err := movies.Keys("tt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "tt1,tt2,tt3" {
+		t.Errorf("Keys(tt) = %v", keys)
+	}
+	if keys, _ := movies.Keys("a*"); len(keys) != 1 || keys[0] != "a*b" {
+		t.Errorf("Keys(a*) = %v; esperado só a*b", keys)
+	}
+
+	if removed, err := movies.Delete("tt1"); err != nil || !removed {
+		t.Errorf("Delete(tt1) = %v, %v", removed, err)
+	}
+	if removed, err := movies.Delete("tt1"); err != nil || removed {
+		t.Errorf("Delete repetido = %v, %v", removed, err)
+	}
+
+	if n, err := movies.DeletePrefix("tt"); err != nil || n != 2 {
+		t.Errorf("DeletePrefix(tt) = %d, %v; esperado 2", n, err)
+	}
+	if n, err := movies.DeletePrefix(""); err != nil || n != 2 {
+		t.Errorf("DeletePrefix(\"\") = %d, %v; esperado 2", n, err)
+	}
+
+	// O outro namespace não é afetado
+	if n, _ := lists.Len(); n != 1 {
+		t.Errorf("lists.Len = %d; esperado 1", n)
+	}
+	if n, _ := movies.Len(); n != 0 {
+		t.Errorf("movies.Len = %d; esperado 0", n)
+	}
+}
+
+func TestRedisClientErrorReplies(t *testing.T) {
+	srv := newRESPServer(t, "")
+	client := newTestRedisClient(t, srv, RedisOptions{})
+
+	// Resposta de erro do servidor vira redisError e a conexão continua no pool
+	_, err := client.do("BOGUS")
+	var replyErr redisError
+	if !errors.As(err, &replyErr) || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("do(BOGUS) = %v; esperado redisError", err)
+	}
+	if reply, err := client.do("PING"); err != nil || reply != "PONG" {
+		t.Fatalf("PING após erro = %v, %v", reply, err)
+	}
+	if srv.connCount() != 1 {
+		t.Errorf("conexões = %d; o erro do servidor não deveria descartar a conexão", srv.connCount())
+	}
+
+	// Senha errada falha na criação do cliente
+	bad := newRESPServer(t, "certa")
+	if _, err := NewRedisClient(RedisOptions{Addr: bad.addr(), Password: "errada"}); err == nil {
+		t.Error("NewRedisClient com senha errada deveria falhar")
+	}
+
+	// Cliente encerrado recusa novos comandos
+	client.Close()
+	if _, err := client.do("PING"); err == nil {
+		t.Error("do após Close deveria falhar")
+	}
+}
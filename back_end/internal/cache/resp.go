@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Erro devolvido pelo próprio servidor Redis (resposta "-ERR ...")
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// Conexão com o servidor falando o protocolo RESP
+type redisConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+	timeout time.Duration
+}
+
+// Abre uma conexão TCP com o servidor
+func dialRedis(addr string, timeout time.Duration) (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &redisConn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		writer:  bufio.NewWriter(conn),
+		timeout: timeout,
+	}, nil
+}
+
+// Envia um comando e lê a resposta; erros de rede invalidam a conexão,
+// mas um redisError deixa a conexão pronta para o próximo comando
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, err
+	}
+
+	// Comandos vão como array de bulk strings: *N\r\n$len\r\narg\r\n...
+	fmt.Fprintf(c.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.writer.Flush(); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// Lê uma resposta: string simples, erro, inteiro, bulk string (nil se ausente) ou array
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: resposta vazia")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: tamanho inválido %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2) // Inclui o \r\n final
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: tamanho inválido %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			// Erros dentro do array (ex: EXEC) não invalidam a conexão
+			item, err := c.readReply()
+			var replyErr redisError
+			if err != nil && !errors.As(err, &replyErr) {
+				return nil, err
+			}
+			if err != nil {
+				items[i] = replyErr
+			} else {
+				items[i] = item
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: resposta desconhecida %q", line)
+}
+
+// Lê uma linha terminada em \r\n (sem o terminador)
+func (c *redisConn) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: linha mal formada %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
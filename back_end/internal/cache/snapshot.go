@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Versão do formato do arquivo de snapshot; arquivos de outra versão são ignorados
+// (v2: valores genéricos em "value" no lugar de "movie")
+const snapshotVersion = 2
+
+// Conteúdo do arquivo de snapshot do cache em memória
+type snapshotDocument[K ~string, V any] struct {
+	Version int                  `json:"version"`
+	Items   []snapshotItem[K, V] `json:"items"` // Do usado mais recentemente ao menos recente
+}
+
+type snapshotItem[K ~string, V any] struct {
+	Key        K         `json:"key"`
+	Value      V         `json:"value"`
+	FreshUntil time.Time `json:"fresh_until"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Grava os valores ainda válidos em SnapshotFile (troca atômica do arquivo)
+func (c *MemoryCache[K, V]) saveSnapshot() error {
+	doc := snapshotDocument[K, V]{Version: snapshotVersion, Items: []snapshotItem[K, V]{}}
+	now := time.Now()
+
+	c.mu.Lock()
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		item := el.Value.(*cacheItem[K, V])
+		if now.After(item.ExpiresAt) {
+			continue
+		}
+		doc.Items = append(doc.Items, snapshotItem[K, V]{
+			Key:        item.Key,
+			Value:      item.Value,
+			FreshUntil: item.FreshUntil,
+			ExpiresAt:  item.ExpiresAt,
+		})
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	path := c.opts.SnapshotFile
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Sem efeito se o rename der certo
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Carrega o snapshot gravado por saveSnapshot, mantendo os tempos de expiração
+// originais; retorna quantos valores entraram no cache
+func (c *MemoryCache[K, V]) loadSnapshot() (int, error) {
+	data, err := os.ReadFile(c.opts.SnapshotFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil // Primeira execução
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var doc snapshotDocument[K, V]
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, fmt.Errorf("snapshot do cache inválido: %w", err)
+	}
+	if doc.Version != snapshotVersion {
+		return 0, fmt.Errorf("snapshot do cache na versão %d, esperada %d", doc.Version, snapshotVersion)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for _, it := range doc.Items {
+		if now.After(it.ExpiresAt) || c.order.Len() >= c.opts.MaxEntries {
+			continue
+		}
+		if _, exists := c.items[it.Key]; exists {
+			continue
+		}
+		// Os itens vêm do mais recente ao menos recente, então entram pelo fundo
+		c.items[it.Key] = c.order.PushBack(&cacheItem[K, V]{
+			Key:        it.Key,
+			Value:      it.Value,
+			FreshUntil: it.FreshUntil,
+			ExpiresAt:  it.ExpiresAt,
+		})
+	}
+	return c.order.Len(), nil
+}
@@ -0,0 +1,159 @@
+package graphql
+
+import (
+	"fmt"
+
+	"movies-api/internal/auth"
+	"movies-api/internal/model"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Papel exigido por campo ("Tipo.campo"); campos fora da tabela são públicos.
+// Para proteger um campo novo basta acrescentá-lo aqui.
+var accessRules = map[string]string{
+	"Query.me":          model.RoleUser,
+	"Query.users":       model.RoleAdmin,
+	"Query.listApiKeys": model.RoleUser,
+	"Query.mySessions":  model.RoleUser,
+	"Query.invites":     model.RoleAdmin,
+	"Query.cacheStats":  model.RoleAdmin,
+	"Query.badMovieIds": model.RoleAdmin,
+
+	"Mutation.logout":           model.RoleUser,
+	"Mutation.logoutAll":        model.RoleUser,
+	"Mutation.revokeSession":    model.RoleUser,
+	"Mutation.updateProfile":    model.RoleUser,
+	"Mutation.changePassword":   model.RoleUser,
+	"Mutation.changeEmail":      model.RoleUser,
+	"Mutation.deleteAccount":    model.RoleUser,
+	"Mutation.createApiKey":     model.RoleUser,
+	"Mutation.revokeApiKey":     model.RoleUser,
+	"Mutation.enableTwoFactor":  model.RoleUser,
+	"Mutation.confirmTwoFactor": model.RoleUser,
+	"Mutation.disableTwoFactor": model.RoleUser,
+	"Mutation.setUserRoles":     model.RoleAdmin,
+	"Mutation.unlockUser":       model.RoleAdmin,
+	"Mutation.createInvite":     model.RoleAdmin,
+	"Mutation.revokeInvite":     model.RoleAdmin,
+	"Mutation.invalidateCache":  model.RoleAdmin,
+	"Mutation.refreshMovie":     model.RoleAdmin,
+}
+
+// Campos que mexem na própria conta (senha, email, 2FA, sessões): exigem login de
+// usuário e são recusados em requisições feitas com chave de API, qualquer que seja o escopo
+var sessionOnlyFields = map[string]bool{
+	"Query.mySessions":          true,
+	"Mutation.logout":           true,
+	"Mutation.logoutAll":        true,
+	"Mutation.revokeSession":    true,
+	"Mutation.updateProfile":    true,
+	"Mutation.changePassword":   true,
+	"Mutation.changeEmail":      true,
+	"Mutation.deleteAccount":    true,
+	"Mutation.enableTwoFactor":  true,
+	"Mutation.confirmTwoFactor": true,
+	"Mutation.disableTwoFactor": true,
+}
+
+// Escopo de chave de API exigido por tipo de operação
+var operationScopes = map[string]string{
+	"Query":    model.ScopeRead,
+	"Mutation": model.ScopeWrite,
+}
+
+// Envolve os resolvers de todos os campos com a checagem de escopo da chave de API,
+// os campos listados nas regras com a checagem de papel e os de sessionOnly com a
+// recusa de chaves de API.
+// Regras que apontam para campos inexistentes são erro, para não deixar nada aberto por engano.
+func enforceAccess(rules map[string]string, sessionOnly map[string]bool, objects ...*graphql.Object) error {
+	matched := make(map[string]bool)
+
+	for _, obj := range objects {
+		scope, ok := operationScopes[obj.Name()]
+		if !ok {
+			return fmt.Errorf("tipo de operação sem escopo definido: %s", obj.Name())
+		}
+
+		for name, field := range obj.Fields() {
+			key := obj.Name() + "." + name
+			role := rules[key]
+			if role != "" {
+				matched[key] = true
+			}
+
+			next := field.Resolve
+			if next == nil {
+				next = graphql.DefaultResolveFn
+			}
+			if sessionOnly[key] {
+				matched[key] = true
+			}
+			field.Resolve = guard(role, scope, obj.Name() == "Mutation", sessionOnly[key], next)
+		}
+	}
+
+	for key := range rules {
+		if !matched[key] {
+			return fmt.Errorf("regra de acesso para campo inexistente: %s", key)
+		}
+	}
+	for key := range sessionOnly {
+		if !matched[key] {
+			return fmt.Errorf("regra de acesso para campo inexistente: %s", key)
+		}
+	}
+	return nil
+}
+
+// Só chama o resolver se a chave de API (quando usada) tiver o escopo e for aceita no
+// campo, se o usuário autenticado tiver o papel (quando exigido) e, em mutations, se o
+// token CSRF conferir quando a requisição usa cookies de sessão
+func guard(role, scope string, mutation, sessionOnly bool, next graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if mutation {
+			if err := auth.CheckCSRF(p.Context); err != nil {
+				return nil, err
+			}
+		}
+
+		key := auth.APIKeyFromContext(p.Context)
+		if key != nil && !key.HasScope(scope) {
+			return nil, insufficientScope(scope)
+		}
+		if key != nil && sessionOnly {
+			return nil, errAPIKeyAccount
+		}
+		if role == "" {
+			return next(p)
+		}
+
+		user, err := auth.UserFromContext(p.Context)
+		if err != nil {
+			return nil, auth.Unauthenticated(err)
+		}
+		if !user.HasRole(role) {
+			return nil, auth.ErrForbidden
+		}
+		// Campos de admin acessados por chave exigem também o escopo admin
+		if role == model.RoleAdmin && key != nil && !key.HasScope(model.ScopeAdmin) {
+			return nil, insufficientScope(model.ScopeAdmin)
+		}
+		return next(p)
+	}
+}
+
+// Campos da própria conta só com login de usuário, nunca com chave de API
+var errAPIKeyAccount = &auth.CodedError{
+	Code:    "FORBIDDEN",
+	Message: "chaves de API não podem alterar a conta nem as sessões",
+}
+
+// Chave de API sem o escopo necessário
+func insufficientScope(scope string) error {
+	return &auth.CodedError{
+		Code:    "INSUFFICIENT_SCOPE",
+		Message: "a chave de API não tem o escopo " + scope,
+		Details: map[string]interface{}{"scope": scope},
+	}
+}
@@ -0,0 +1,318 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"movies-api/internal/auth"
+	"movies-api/internal/model"
+)
+
+// Cadastra o usuário e envia o link de confirmação de email
+func (r *Resolver) Signup(ctx context.Context, name, email, password, inviteCode string) (*model.User, error) {
+	user, err := r.Store.Signup(name, email, password, inviteCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// A conta já existe; se o email falhar, o usuário pode pedir reenvio
+	if err := r.Store.SendVerificationEmail(ctx, user.Email); err != nil {
+		log.Printf("Erro ao enviar email de verificação para %s: %v", user.Email, err)
+	}
+	return user, nil
+}
+
+// Confirma o email a partir do token do link enviado
+func (r *Resolver) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	return r.Store.VerifyEmail(ctx, token)
+}
+
+// Reenvia o link de confirmação; sempre true para não revelar emails cadastrados
+func (r *Resolver) ResendVerificationEmail(ctx context.Context, email string) (bool, error) {
+	if err := r.Store.SendVerificationEmail(ctx, email); err != nil {
+		log.Printf("Erro ao reenviar email de verificação para %s: %v", email, err)
+	}
+	return true, nil
+}
+
+// Valida as credenciais e abre uma nova sessão; com cookie=true os tokens vão em
+// cookies HttpOnly em vez do corpo da resposta
+func (r *Resolver) Login(ctx context.Context, email, password string, cookie bool) (map[string]interface{}, error) {
+	if cookie && !auth.CookiesEnabled(ctx) {
+		return nil, auth.ErrCookiesDisabled
+	}
+
+	user, err := r.Store.Login(ctx, email, password)
+	var coded *auth.CodedError
+	if errors.As(err, &coded) {
+		return nil, err // Bloqueio, espera ou email não confirmado: o front end mostra pelo código
+	}
+	if err != nil {
+		return nil, errors.New("credenciais inválidas")
+	}
+
+	// Com dois fatores ativos a senha só rende um desafio, trocado em verifyTwoFactor
+	if user.TwoFactorEnabled {
+		challenge, err := r.Store.StartTwoFactorChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"email":             user.Email,
+			"twoFactorRequired": true,
+			"challengeToken":    challenge,
+		}, nil
+	}
+
+	pair, err := r.Store.StartSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return sessionResponse(ctx, user, pair, cookie)
+}
+
+// Conclui o login em dois fatores com o código do app autenticador ou de recuperação
+func (r *Resolver) VerifyTwoFactor(ctx context.Context, challengeToken, code string, cookie bool) (map[string]interface{}, error) {
+	if cookie && !auth.CookiesEnabled(ctx) {
+		return nil, auth.ErrCookiesDisabled
+	}
+
+	user, err := r.Store.VerifyTwoFactor(ctx, challengeToken, code)
+	if err != nil {
+		return nil, err
+	}
+
+	pair, err := r.Store.StartSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return sessionResponse(ctx, user, pair, cookie)
+}
+
+// Troca o refresh token por um novo par de tokens. Sem refreshToken, usa o do cookie
+// e devolve os novos tokens também em cookies.
+func (r *Resolver) RefreshToken(ctx context.Context, refreshToken string, cookie bool) (map[string]interface{}, error) {
+	if refreshToken == "" {
+		refreshToken, cookie = auth.RefreshTokenFromCookie(ctx), true
+		if refreshToken == "" {
+			return nil, auth.ErrInvalidRefreshToken
+		}
+	}
+	if cookie && !auth.CookiesEnabled(ctx) {
+		return nil, auth.ErrCookiesDisabled
+	}
+
+	pair, err := r.Store.Refresh(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// O access token recém-emitido identifica o dono da sessão
+	claims, err := auth.ParseToken(pair.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.Store.GetByID(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	return sessionResponse(ctx, user, pair, cookie)
+}
+
+// Encerra a sessão do token usado na requisição
+func (r *Resolver) Logout(ctx context.Context) (bool, error) {
+	sessionID, err := auth.SessionFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.Store.Logout(sessionID); err != nil {
+		return false, err
+	}
+	auth.ClearSessionCookies(ctx)
+	return true, nil
+}
+
+// Encerra todas as sessões do usuário autenticado
+func (r *Resolver) LogoutAll(ctx context.Context) (bool, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.Store.LogoutAll(user.ID); err != nil {
+		return false, err
+	}
+	auth.ClearSessionCookies(ctx)
+	return true, nil
+}
+
+// Lista as sessões ativas do usuário autenticado
+func (r *Resolver) MySessions(ctx context.Context) ([]map[string]interface{}, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, _ := auth.SessionFromContext(ctx)
+
+	var list []map[string]interface{}
+	for _, session := range r.Store.ListSessions(user.ID) {
+		list = append(list, map[string]interface{}{
+			"id":         session.ID,
+			"userAgent":  session.UserAgent,
+			"ip":         session.IP,
+			"createdAt":  session.CreatedAt.Format(time.RFC3339),
+			"lastSeenAt": session.LastSeenAt.Format(time.RFC3339),
+			"expiresAt":  session.ExpiresAt.Format(time.RFC3339),
+			"current":    session.ID == current, // Sessão usada nesta requisição
+		})
+	}
+	return list, nil
+}
+
+// Encerra uma sessão do usuário autenticado, por exemplo de um dispositivo desconhecido
+func (r *Resolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.Store.RevokeSession(user.ID, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Envia o link de redefinição de senha; sempre responde true para não revelar emails cadastrados
+func (r *Resolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
+	if err := r.Store.RequestPasswordReset(ctx, email); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Define uma nova senha a partir do token recebido por email
+func (r *Resolver) ResetPassword(ctx context.Context, token, newPassword string) (bool, error) {
+	if err := r.Store.ResetPassword(token, newPassword); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Desbloqueia a conta pelo link enviado no email de bloqueio
+func (r *Resolver) UnlockAccount(ctx context.Context, token string) (bool, error) {
+	if err := r.Store.UnlockAccount(token); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Altera o nome do usuário autenticado
+func (r *Resolver) UpdateProfile(ctx context.Context, name string) (*model.User, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Store.UpdateProfile(user.ID, name)
+}
+
+// Troca a senha mantendo apenas a sessão atual aberta
+func (r *Resolver) ChangePassword(ctx context.Context, currentPassword, newPassword string) (bool, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	sessionID, _ := auth.SessionFromContext(ctx)
+	if err := r.Store.ChangePassword(user.ID, sessionID, currentPassword, newPassword); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Envia o link de confirmação para o novo email
+func (r *Resolver) ChangeEmail(ctx context.Context, newEmail, password string) (bool, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.Store.ChangeEmail(ctx, user.ID, newEmail, password); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Exclui a conta do usuário autenticado e todos os dados dela
+func (r *Resolver) DeleteAccount(ctx context.Context, password string) (bool, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.Store.DeleteAccount(user.ID, password); err != nil {
+		return false, err
+	}
+	auth.ClearSessionCookies(ctx)
+	return true, nil
+}
+
+// Gera o segredo do app autenticador; só vale depois de confirmTwoFactor
+func (r *Resolver) EnableTwoFactor(ctx context.Context, password string) (map[string]interface{}, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	setup, err := r.Store.BeginTwoFactorSetup(user.ID, password)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"secret":          setup.Secret,
+		"provisioningUri": setup.URI,
+	}, nil
+}
+
+// Ativa o segundo fator e devolve os códigos de recuperação (exibidos uma única vez)
+func (r *Resolver) ConfirmTwoFactor(ctx context.Context, code string) ([]string, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Store.ConfirmTwoFactor(user.ID, code)
+}
+
+// Desativa o segundo fator
+func (r *Resolver) DisableTwoFactor(ctx context.Context, password, code string) (bool, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.Store.DisableTwoFactor(user.ID, password, code); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Entrega os tokens no corpo ou, no modo cookie, em cookies HttpOnly
+// (aí o corpo não traz tokens, para um script injetado não conseguir lê-los)
+func sessionResponse(ctx context.Context, user *model.User, pair *auth.TokenPair, cookie bool) (map[string]interface{}, error) {
+	resp := tokenResponse(user, pair)
+	if !cookie {
+		return resp, nil
+	}
+	if err := auth.IssueSessionCookies(ctx, pair); err != nil {
+		return nil, err
+	}
+	delete(resp, "token")
+	delete(resp, "refreshToken")
+	return resp, nil
+}
+
+// Monta a resposta das mutations login e refreshToken
+func tokenResponse(user *model.User, pair *auth.TokenPair) map[string]interface{} {
+	return map[string]interface{}{
+		"email":        user.Email,
+		"token":        pair.AccessToken,
+		"refreshToken": pair.RefreshToken,
+		"expiresAt":    pair.ExpiresAt.Format(time.RFC3339),
+
+		"twoFactorRequired": false,
+	}
+}
@@ -0,0 +1,92 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"movies-api/internal/auth"
+	"movies-api/internal/model"
+)
+
+// Chaves de API só são gerenciadas com login de usuário, nunca com outra chave
+var errAPIKeyManagement = &auth.CodedError{
+	Code:    "FORBIDDEN",
+	Message: "chaves de API não podem gerenciar chaves de API",
+}
+
+// Cria uma chave para o usuário autenticado ou, para admins, para outro usuário
+func (r *Resolver) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt, userID string) (map[string]interface{}, error) {
+	ownerID, err := apiKeyOwner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var expires *time.Time
+	if expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, errors.New("expiresAt deve estar no formato RFC 3339")
+		}
+		expires = &t
+	}
+
+	raw, key, err := r.Store.CreateAPIKey(ownerID, name, scopes, expires)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"key":    raw, // Mostrada uma única vez
+		"apiKey": key,
+	}, nil
+}
+
+// Lista as chaves do usuário autenticado ou, para admins, de outro usuário
+func (r *Resolver) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	ownerID, err := apiKeyOwner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return r.Store.ListAPIKeys(ownerID), nil
+}
+
+// Revoga uma chave do próprio usuário (admins podem revogar qualquer chave)
+func (r *Resolver) RevokeAPIKey(ctx context.Context, id string) (bool, error) {
+	key, err := r.Store.GetAPIKey(id)
+	if err != nil {
+		return false, err
+	}
+	if _, err := apiKeyOwner(ctx, key.UserID); err != nil {
+		return false, err
+	}
+	if err := r.Store.RevokeAPIKey(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Resolve de quem são as chaves da operação: o próprio usuário ou, se admin, o userID informado
+func apiKeyOwner(ctx context.Context, userID string) (string, error) {
+	if auth.APIKeyFromContext(ctx) != nil {
+		return "", errAPIKeyManagement
+	}
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if userID == "" || userID == user.ID {
+		return user.ID, nil
+	}
+	if !user.HasRole(model.RoleAdmin) {
+		return "", auth.ErrForbidden
+	}
+	return userID, nil
+}
+
+// Formata datas opcionais das chaves para o GraphQL
+func formatOptionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,127 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"movies-api/internal/model"
+)
+
+// Estatísticas do cache de filmes (somente admin, ver accessRules)
+func (r *Resolver) CacheStats(ctx context.Context) (map[string]interface{}, error) {
+	entries, err := r.Cache.Len()
+	if err != nil {
+		return nil, err
+	}
+	lists, err := r.Lists.Len()
+	if err != nil {
+		return nil, err
+	}
+	failures, err := r.Failures.Len()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := r.movies.Stats()
+	lookups := stats.Hits + stats.StaleHits + stats.Misses
+	hitRatio := 0.0
+	if lookups > 0 {
+		hitRatio = float64(stats.Hits+stats.StaleHits) / float64(lookups)
+	}
+	return map[string]interface{}{
+		"entries":     entries,
+		"lists":       lists,
+		"failures":    failures,
+		"hits":        stats.Hits,
+		"staleHits":   stats.StaleHits,
+		"misses":      stats.Misses,
+		"hitRatio":    hitRatio,
+		"coalesced":   stats.Coalesced,
+		"fetches":     stats.Fetches,
+		"fetchErrors": stats.FetchErrors,
+
+		"negativeHits": stats.NegativeHits,
+	}, nil
+}
+
+// Remove do cache um filme (id), os filmes com um prefixo (prefix) ou tudo (all);
+// exatamente uma das opções deve ser informada. Falhas guardadas no cache negativo
+// saem junto e contam no total retornado; as listas calculadas são sempre descartadas.
+func (r *Resolver) InvalidateCache(ctx context.Context, id, prefix string, all bool) (int, error) {
+	chosen := 0
+	for _, set := range []bool{id != "", prefix != "", all} {
+		if set {
+			chosen++
+		}
+	}
+	if chosen != 1 {
+		return 0, errors.New("informe exatamente um entre id, prefix e all")
+	}
+
+	defer r.invalidateLists()
+
+	// Buscas à OMDb já em andamento não regravam o que for apagado aqui
+	removed := 0
+	err := r.movies.Invalidate(func() error {
+		var err error
+		removed, err = r.removeCached(id, prefix)
+		return err
+	})
+	return removed, err
+}
+
+// Apaga do cache de filmes e do cache negativo o id ou as chaves com o prefixo;
+// retorna quantos valores saíram
+func (r *Resolver) removeCached(id, prefix string) (int, error) {
+	if id != "" {
+		removed, err := r.Cache.Delete(id)
+		if err != nil {
+			return 0, err
+		}
+		failed, err := r.Failures.Delete(id)
+		if err != nil || !(removed || failed) {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	// prefix ou all (prefix vazio remove tudo)
+	removed, err := r.Cache.DeletePrefix(prefix)
+	if err != nil {
+		return 0, err
+	}
+	failed, err := r.Failures.DeletePrefix(prefix)
+	return removed + failed, err
+}
+
+// IDs que a OMDb não reconheceu e ainda estão no cache negativo, ordenados (somente admin)
+func (r *Resolver) BadMovieIDs(ctx context.Context) ([]map[string]interface{}, error) {
+	failures, err := r.movies.Failures()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(failures))
+	for id := range failures {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	report := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		report = append(report, map[string]interface{}{
+			"id":       id,
+			"error":    failures[id].Error,
+			"failedAt": failures[id].FailedAt.Format(time.RFC3339),
+		})
+	}
+	return report, nil
+}
+
+// Busca o filme de novo na OMDb e substitui a versão do cache (somente admin);
+// sempre faz uma requisição nova, mesmo que outra busca do filme esteja em andamento
+func (r *Resolver) RefreshMovie(ctx context.Context, id string) (*model.Movie, error) {
+	return r.movies.ForceReload(id)
+}
@@ -0,0 +1,43 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"movies-api/internal/auth"
+)
+
+// Cria um convite de cadastro (somente admin, ver accessRules)
+func (r *Resolver) CreateInvite(ctx context.Context, maxUses int, expiresAt string) (map[string]interface{}, error) {
+	user, err := auth.UserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var expires *time.Time
+	if expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, errors.New("expiresAt deve estar no formato RFC 3339")
+		}
+		expires = &t
+	}
+
+	code, invite, err := r.Store.CreateInvite(user.ID, maxUses, expires)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"code":   code, // Mostrado uma única vez
+		"invite": invite,
+	}, nil
+}
+
+// Revoga um convite (somente admin)
+func (r *Resolver) RevokeInvite(ctx context.Context, id string) (bool, error) {
+	if err := r.Store.RevokeInvite(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -2,6 +2,8 @@ package graphql
 
 import (
 	"context"
+	"errors"
+	"log"
 	"math/rand"
 	"movies-api/internal/auth"
 	"movies-api/internal/cache"
@@ -9,37 +11,104 @@ import (
 	"movies-api/internal/omdb"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Estrutura que contém as dependências usadas pelo GraphQL
 type Resolver struct {
-	Cache *cache.Cache // Cache para armazenar filmes e evitar requisições repetidas
-	OMDb  *omdb.Client // Cliente para consumir a OMDb API
-	Store *auth.Store  // Armazena usuários e senhas (signup/login)
+	Cache cache.MovieCache // Cache para armazenar filmes e evitar requisições repetidas
+	Lists cache.ListCache  // Listas já calculadas (rankings, filtros por gênero)
+	OMDb  *omdb.Client     // Cliente para consumir a OMDb API
+	Store *auth.Store      // Armazena usuários e senhas (signup/login)
+
+	Failures cache.FailureCache // IDs que a OMDb não conhece, para não repetir a busca a cada requisição
+
+	movies *cache.Loader[string, *model.Movie] // Leitura de filmes: cache → OMDb, sem buscas duplicadas
+
+	listMu  sync.Mutex    // Serializa gravar uma lista e invalidar todas
+	listGen atomic.Uint64 // Muda a cada invalidação das listas
 }
 
 // Construtor que injeta as dependências no resolver
-func NewResolver(c *cache.Cache, o *omdb.Client, s *auth.Store) *Resolver {
-	return &Resolver{
-		Cache: c,
-		OMDb:  o,
-		Store: s,
+func NewResolver(c cache.MovieCache, l cache.ListCache, f cache.FailureCache, o *omdb.Client, s *auth.Store) *Resolver {
+	r := &Resolver{
+		Cache:    c,
+		Lists:    l,
+		OMDb:     o,
+		Store:    s,
+		Failures: f,
 	}
+	r.movies = cache.NewLoader(c, r.fetchMovie)
+	// Só "ID inexistente" é definitivo; falhas de rede, cota ou chave são tentadas de novo
+	r.movies.CacheFailures(f, func(err error) bool { return errors.Is(err, omdb.ErrNotFound) })
+	// Listas só usam os filmes do catálogo; buscas de outros IDs não as invalidam
+	r.movies.OnChange(func(id string) {
+		if isCatalogMovie(id) {
+			r.invalidateLists()
+		}
+	})
+	return r
 }
 
 // Busca um único filme pelo ID (cache → OMDb → adapta e salva)
 func (r *Resolver) GetMovieByID(ctx context.Context, id string) (*model.Movie, error) {
-	if movie, found := r.Cache.Get(id); found {
-		return movie, nil // Retorna do cache, se disponível
+	return r.loadMovie(id)
+}
+
+// Busca um filme no cache ou, na falta, na OMDb; misses simultâneos
+// do mesmo ID compartilham uma única requisição
+func (r *Resolver) loadMovie(id string) (*model.Movie, error) {
+	return r.movies.Load(id)
+}
+
+// Pré-carrega o catálogo inteiro no cache com no máximo workers requisições
+// simultâneas à OMDb; retorna quantos filmes falharam
+func (r *Resolver) WarmUp(workers int) (total, failed int) {
+	ids := getStaticMovieIDs()
+	return len(ids), r.movies.Warm(ids, workers)
+}
+
+// Retorna a lista guardada ou a calcula e guarda. Se algum filme mudar durante
+// o cálculo, a lista é devolvida mas não guardada, para não guardar dados antigos.
+func (r *Resolver) cachedList(key string, compute func() ([]*model.Movie, error)) ([]*model.Movie, error) {
+	if entry, found := r.Lists.Get(key); found {
+		return entry.Value, nil
+	}
+
+	gen := r.listGen.Load()
+	movies, err := compute()
+	if err != nil {
+		return nil, err
+	}
+
+	r.listMu.Lock()
+	defer r.listMu.Unlock()
+	if r.listGen.Load() == gen {
+		r.Lists.Set(key, movies)
+	}
+	return movies, nil
+}
+
+// Descarta as listas calculadas; chamado quando algum filme entra, muda ou sai do cache
+func (r *Resolver) invalidateLists() {
+	r.listMu.Lock()
+	defer r.listMu.Unlock()
+
+	r.listGen.Add(1)
+	if _, err := r.Lists.DeletePrefix(""); err != nil {
+		log.Printf("Erro ao invalidar as listas em cache: %v", err)
 	}
-	raw, err := r.OMDb.FetchMovieByID(id) // Busca na OMDb
+}
+
+// Busca o filme na OMDb e adapta para o modelo interno
+func (r *Resolver) fetchMovie(id string) (*model.Movie, error) {
+	raw, err := r.OMDb.FetchMovieByID(id)
 	if err != nil {
 		return nil, err
 	}
-	movie := omdb.AdaptMovie(raw) // Adapta para o modelo interno
-	r.Cache.Set(id, movie)        // Armazena no cache
-	return movie, nil
+	return omdb.AdaptMovie(raw), nil
 }
 
 // Retorna todos os filmes ordenados da data mais recente para mais antiga
@@ -48,14 +117,9 @@ func (r *Resolver) GetRecentMovies(ctx context.Context) ([]*model.Movie, error)
 	var allMovies []*model.Movie
 
 	for _, id := range ids {
-		movie, found := r.Cache.Get(id) // Tenta usar o cache
-		if !found {
-			raw, err := r.OMDb.FetchMovieByID(id) // Busca da OMDb
-			if err != nil {
-				continue // Pula filmes com erro
-			}
-			movie = omdb.AdaptMovie(raw)
-			r.Cache.Set(id, movie)
+		movie, err := r.loadMovie(id) // Cache → OMDb
+		if err != nil {
+			continue // Pula filmes com erro
 		}
 		allMovies = append(allMovies, movie)
 	}
@@ -88,31 +152,28 @@ func (r *Resolver) GetRecentMovies(ctx context.Context) ([]*model.Movie, error)
 
 // Retorna os 10 filmes com maior nota da crítica
 func (r *Resolver) GetTopRatedByCritic(ctx context.Context) ([]*model.Movie, error) {
-	ids := getStaticMovieIDs()
-	var movies []*model.Movie
+	return r.cachedList("topRatedByCritic", func() ([]*model.Movie, error) {
+		ids := getStaticMovieIDs()
+		var movies []*model.Movie
 
-	for _, id := range ids {
-		movie, found := r.Cache.Get(id)
-		if !found {
-			raw, err := r.OMDb.FetchMovieByID(id)
+		for _, id := range ids {
+			movie, err := r.loadMovie(id) // Cache → OMDb
 			if err != nil {
-				continue
+				continue // Pula filmes com erro
 			}
-			movie = omdb.AdaptMovie(raw)
-			r.Cache.Set(id, movie)
+			movies = append(movies, movie)
 		}
-		movies = append(movies, movie)
-	}
 
-	// Ordena por nota da crítica (maior primeiro)
-	sort.Slice(movies, func(i, j int) bool {
-		return movies[i].CriticRating > movies[j].CriticRating
-	})
+		// Ordena por nota da crítica (maior primeiro)
+		sort.Slice(movies, func(i, j int) bool {
+			return movies[i].CriticRating > movies[j].CriticRating
+		})
 
-	if len(movies) > 10 {
-		return movies[:10], nil
-	}
-	return movies, nil
+		if len(movies) > 10 {
+			return movies[:10], nil
+		}
+		return movies, nil
+	})
 }
 
 // Retorna os 10 filmes com maior nota dos usuários
@@ -121,14 +182,9 @@ func (r *Resolver) GetTopRatedByUsers(ctx context.Context) ([]*model.Movie, erro
 	var movies []*model.Movie
 
 	for _, id := range ids {
-		movie, found := r.Cache.Get(id)
-		if !found {
-			raw, err := r.OMDb.FetchMovieByID(id)
-			if err != nil {
-				continue
-			}
-			movie = omdb.AdaptMovie(raw)
-			r.Cache.Set(id, movie)
+		movie, err := r.loadMovie(id) // Cache → OMDb
+		if err != nil {
+			continue // Pula filmes com erro
 		}
 		movies = append(movies, movie)
 	}
@@ -146,63 +202,57 @@ func (r *Resolver) GetTopRatedByUsers(ctx context.Context) ([]*model.Movie, erro
 
 // Retorna filmes com nota alta tanto da crítica quanto dos usuários
 func (r *Resolver) GetLovedByAll(ctx context.Context) ([]*model.Movie, error) {
-	ids := getStaticMovieIDs()
-	var movies []*model.Movie
+	return r.cachedList("lovedByAll", func() ([]*model.Movie, error) {
+		ids := getStaticMovieIDs()
+		var movies []*model.Movie
 
-	for _, id := range ids {
-		movie, found := r.Cache.Get(id)
-		if !found {
-			raw, err := r.OMDb.FetchMovieByID(id)
+		for _, id := range ids {
+			movie, err := r.loadMovie(id) // Cache → OMDb
 			if err != nil {
-				continue
+				continue // Pula filmes com erro
 			}
-			movie = omdb.AdaptMovie(raw)
-			r.Cache.Set(id, movie)
-		}
 
-		// Verifica se atende os critérios de "amado por todos"
-		if movie.CriticRating >= 80 && movie.UserRating >= 8.0 {
-			movies = append(movies, movie)
+			// Verifica se atende os critérios de "amado por todos"
+			if movie.CriticRating >= 80 && movie.UserRating >= 8.0 {
+				movies = append(movies, movie)
+			}
 		}
-	}
 
-	// Ordena por média ponderada entre crítica e usuários
-	sort.Slice(movies, func(i, j int) bool {
-		mi := float64(movies[i].CriticRating)/10 + movies[i].UserRating
-		mj := float64(movies[j].CriticRating)/10 + movies[j].UserRating
-		return mi > mj
-	})
+		// Ordena por média ponderada entre crítica e usuários
+		sort.Slice(movies, func(i, j int) bool {
+			mi := float64(movies[i].CriticRating)/10 + movies[i].UserRating
+			mj := float64(movies[j].CriticRating)/10 + movies[j].UserRating
+			return mi > mj
+		})
 
-	return movies, nil
+		return movies, nil
+	})
 }
 
 // Retorna todos os filmes que pertencem a um gênero específico
 func (r *Resolver) GetByGenre(ctx context.Context, genre string) ([]*model.Movie, error) {
-	ids := getStaticMovieIDs()
-	var movies []*model.Movie
+	genre = strings.ToLower(genre) // Normaliza para comparação (e para a chave do cache)
 
-	genre = strings.ToLower(genre) // Normaliza para comparação
+	return r.cachedList("genre:"+genre, func() ([]*model.Movie, error) {
+		ids := getStaticMovieIDs()
+		var movies []*model.Movie
 
-	for _, id := range ids {
-		movie, found := r.Cache.Get(id)
-		if !found {
-			raw, err := r.OMDb.FetchMovieByID(id)
+		for _, id := range ids {
+			movie, err := r.loadMovie(id) // Cache → OMDb
 			if err != nil {
-				continue
+				continue // Pula filmes com erro
 			}
-			movie = omdb.AdaptMovie(raw)
-			r.Cache.Set(id, movie)
-		}
 
-		for _, g := range movie.Genres {
-			if strings.ToLower(g) == genre {
-				movies = append(movies, movie)
-				break
+			for _, g := range movie.Genres {
+				if strings.ToLower(g) == genre {
+					movies = append(movies, movie)
+					break
+				}
 			}
 		}
-	}
 
-	return movies, nil
+		return movies, nil
+	})
 }
 
 // Retorna um filme aleatório com base nos gêneros informados
@@ -221,14 +271,9 @@ func (r *Resolver) GetRandomFromGenres(ctx context.Context, generos []string) (*
 	}
 
 	for _, id := range ids {
-		movie, found := r.Cache.Get(id)
-		if !found {
-			raw, err := r.OMDb.FetchMovieByID(id)
-			if err != nil {
-				continue
-			}
-			movie = omdb.AdaptMovie(raw)
-			r.Cache.Set(id, movie)
+		movie, err := r.loadMovie(id) // Cache → OMDb
+		if err != nil {
+			continue // Pula filmes com erro
 		}
 
 		// Verifica se o filme possui ao menos um dos gêneros buscados
@@ -263,14 +308,9 @@ func (r *Resolver) GetAllMovies(ctx context.Context) ([]*model.Movie, error) {
 		}
 		seen[id] = true
 
-		movie, found := r.Cache.Get(id)
-		if !found {
-			raw, err := r.OMDb.FetchMovieByID(id)
-			if err != nil {
-				continue
-			}
-			movie = omdb.AdaptMovie(raw)
-			r.Cache.Set(id, movie)
+		movie, err := r.loadMovie(id) // Cache → OMDb
+		if err != nil {
+			continue // Pula filmes com erro
 		}
 		allMovies = append(allMovies, movie)
 	}
@@ -278,6 +318,25 @@ func (r *Resolver) GetAllMovies(ctx context.Context) ([]*model.Movie, error) {
 	return allMovies, nil
 }
 
+// Retorna o usuário autenticado na requisição
+func (r *Resolver) Me(ctx context.Context) (*model.User, error) {
+	return auth.UserFromContext(ctx)
+}
+
+// IDs do catálogo em conjunto, para consultas rápidas
+var catalogIDs = func() map[string]bool {
+	set := make(map[string]bool)
+	for _, id := range getStaticMovieIDs() {
+		set[id] = true
+	}
+	return set
+}()
+
+// Indica se o filme faz parte do catálogo (e portanto das listas calculadas)
+func isCatalogMovie(id string) bool {
+	return catalogIDs[id]
+}
+
 // IDs estáticos de filmes utilizados como mock/base de dados
 func getStaticMovieIDs() []string {
 	return []string{
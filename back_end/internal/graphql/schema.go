@@ -1,8 +1,9 @@
 package graphql
 
 import (
-	"errors"
-	"movies-api/internal/auth"
+	"time"
+
+	"movies-api/internal/model"
 
 	"github.com/graphql-go/graphql"
 )
@@ -31,15 +32,139 @@ func NewSchema(resolver *Resolver) (graphql.Schema, error) {
 			"id":    &graphql.Field{Type: graphql.String},
 			"name":  &graphql.Field{Type: graphql.String},
 			"email": &graphql.Field{Type: graphql.String},
+
+			"email_verified": &graphql.Field{Type: graphql.Boolean},
+			"roles":          &graphql.Field{Type: graphql.NewList(graphql.String)},
+
+			"two_factor_enabled": &graphql.Field{Type: graphql.Boolean},
 		},
 	})
 
-	// Tipo retornado pela mutation login (email + token)
+	// Tipo retornado pelas mutations login e refreshToken
 	loginResponseType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "LoginResponse",
 		Fields: graphql.Fields{
-			"email": &graphql.Field{Type: graphql.String},
-			"token": &graphql.Field{Type: graphql.String},
+			"email":        &graphql.Field{Type: graphql.String},
+			"token":        &graphql.Field{Type: graphql.String}, // Access token (vida curta)
+			"refreshToken": &graphql.Field{Type: graphql.String}, // Usado uma única vez em refreshToken
+			"expiresAt":    &graphql.Field{Type: graphql.String}, // Expiração do access token (RFC 3339)
+
+			// Com dois fatores ativos o login não traz tokens, só o desafio para verifyTwoFactor
+			"twoFactorRequired": &graphql.Field{Type: graphql.Boolean},
+			"challengeToken":    &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	// Sessão de login aberta em um dispositivo
+	sessionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Session",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.String},
+			"userAgent":  &graphql.Field{Type: graphql.String},
+			"ip":         &graphql.Field{Type: graphql.String},
+			"createdAt":  &graphql.Field{Type: graphql.String}, // RFC 3339
+			"lastSeenAt": &graphql.Field{Type: graphql.String}, // RFC 3339
+			"expiresAt":  &graphql.Field{Type: graphql.String}, // Expiração do refresh token (RFC 3339)
+			"current":    &graphql.Field{Type: graphql.Boolean},
+		},
+	})
+
+	// Dados para cadastrar a conta no app autenticador
+	twoFactorSetupType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TwoFactorSetup",
+		Fields: graphql.Fields{
+			"secret":          &graphql.Field{Type: graphql.String}, // Para digitar manualmente
+			"provisioningUri": &graphql.Field{Type: graphql.String}, // otpauth://, para o QR code
+		},
+	})
+
+	// Chave de API (o segredo nunca é retornado, só na criação)
+	apiKeyType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ApiKey",
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.String},
+			"userId": &graphql.Field{Type: graphql.String, Resolve: apiKeyField(func(k *model.APIKey) interface{} { return k.UserID })},
+			"name":   &graphql.Field{Type: graphql.String},
+			"scopes": &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.String, Resolve: apiKeyField(func(k *model.APIKey) interface{} {
+				return formatOptionalTime(&k.CreatedAt)
+			})},
+			"expiresAt": &graphql.Field{Type: graphql.String, Resolve: apiKeyField(func(k *model.APIKey) interface{} {
+				return formatOptionalTime(k.ExpiresAt)
+			})},
+			"lastUsedAt": &graphql.Field{Type: graphql.String, Resolve: apiKeyField(func(k *model.APIKey) interface{} {
+				return formatOptionalTime(k.LastUsedAt)
+			})},
+		},
+	})
+
+	// Resposta de createApiKey: a chave completa aparece só aqui
+	createAPIKeyResponseType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CreateApiKeyResponse",
+		Fields: graphql.Fields{
+			"key":    &graphql.Field{Type: graphql.String},
+			"apiKey": &graphql.Field{Type: apiKeyType},
+		},
+	})
+
+	// Convite de cadastro (o código nunca é retornado, só na criação)
+	inviteType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Invite",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.String},
+			"createdBy": &graphql.Field{Type: graphql.String, Resolve: inviteField(func(i *model.Invite) interface{} { return i.CreatedBy })},
+			"maxUses":   &graphql.Field{Type: graphql.Int, Resolve: inviteField(func(i *model.Invite) interface{} { return i.MaxUses })},
+			"uses":      &graphql.Field{Type: graphql.Int},
+			"createdAt": &graphql.Field{Type: graphql.String, Resolve: inviteField(func(i *model.Invite) interface{} {
+				return formatOptionalTime(&i.CreatedAt)
+			})},
+			"expiresAt": &graphql.Field{Type: graphql.String, Resolve: inviteField(func(i *model.Invite) interface{} {
+				return formatOptionalTime(i.ExpiresAt)
+			})},
+			"revokedAt": &graphql.Field{Type: graphql.String, Resolve: inviteField(func(i *model.Invite) interface{} {
+				return formatOptionalTime(i.RevokedAt)
+			})},
+			"active": &graphql.Field{Type: graphql.Boolean, Resolve: inviteField(func(i *model.Invite) interface{} {
+				return i.Active(time.Now())
+			})},
+		},
+	})
+
+	// Resposta de createInvite: o código aparece só aqui
+	createInviteResponseType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CreateInviteResponse",
+		Fields: graphql.Fields{
+			"code":   &graphql.Field{Type: graphql.String},
+			"invite": &graphql.Field{Type: inviteType},
+		},
+	})
+
+	// Estatísticas do cache de filmes (contadores desde o início do processo)
+	cacheStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CacheStats",
+		Fields: graphql.Fields{
+			"entries":     &graphql.Field{Type: graphql.Int},
+			"lists":       &graphql.Field{Type: graphql.Int}, // Listas calculadas em cache
+			"failures":    &graphql.Field{Type: graphql.Int}, // IDs no cache negativo
+			"hits":        &graphql.Field{Type: graphql.Int},
+			"staleHits":   &graphql.Field{Type: graphql.Int},
+			"misses":      &graphql.Field{Type: graphql.Int},
+			"hitRatio":    &graphql.Field{Type: graphql.Float},
+			"coalesced":   &graphql.Field{Type: graphql.Int},
+			"fetches":     &graphql.Field{Type: graphql.Int},
+			"fetchErrors": &graphql.Field{Type: graphql.Int},
+
+			"negativeHits": &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	// ID que a OMDb não reconheceu (relatório do cache negativo)
+	badMovieIDType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BadMovieId",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.String},
+			"error":    &graphql.Field{Type: graphql.String},
+			"failedAt": &graphql.Field{Type: graphql.String},
 		},
 	})
 
@@ -105,6 +230,66 @@ func NewSchema(resolver *Resolver) (graphql.Schema, error) {
 					return resolver.GetRandomFromGenres(p.Context, generosStr)
 				},
 			},
+			// Usuário dono do token enviado no header Authorization
+			"me": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.Me(p.Context)
+				},
+			},
+			// Lista de usuários (somente admin, ver accessRules)
+			"users": &graphql.Field{
+				Type: graphql.NewList(userType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.Store.ListUsers(), nil
+				},
+			},
+			// Modo de cadastro (open, invite ou closed), para o front end decidir se pede convite
+			"registrationMode": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.Store.RegistrationMode(), nil
+				},
+			},
+			// Convites de cadastro (somente admin)
+			"invites": &graphql.Field{
+				Type: graphql.NewList(inviteType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.Store.ListInvites(), nil
+				},
+			},
+			// Estatísticas do cache de filmes (somente admin)
+			"cacheStats": &graphql.Field{
+				Type: cacheStatsType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.CacheStats(p.Context)
+				},
+			},
+			// IDs que a OMDb não reconheceu, ainda no cache negativo (somente admin)
+			"badMovieIds": &graphql.Field{
+				Type: graphql.NewList(badMovieIDType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.BadMovieIDs(p.Context)
+				},
+			},
+			// Sessões ativas do usuário autenticado
+			"mySessions": &graphql.Field{
+				Type: graphql.NewList(sessionType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.MySessions(p.Context)
+				},
+			},
+			// Chaves de API do usuário (admins podem informar outro userId)
+			"listApiKeys": &graphql.Field{
+				Type: graphql.NewList(apiKeyType),
+				Args: graphql.FieldConfigArgument{
+					"userId": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					userID, _ := p.Args["userId"].(string)
+					return resolver.ListAPIKeys(p.Context, userID)
+				},
+			},
 			// ✅ Nova query para retornar todos os filmes (sem filtro)
 			"allMovies": &graphql.Field{
 				Type: graphql.NewList(movieType),
@@ -115,24 +300,44 @@ func NewSchema(resolver *Resolver) (graphql.Schema, error) {
 		},
 	})
 
-	// Define as mutations (signup e login)
+	// Define as mutations (cadastro, login e sessões)
 	mutationType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Mutation",
 		Fields: graphql.Fields{
 			"signup": &graphql.Field{
 				Type: userType,
 				Args: graphql.FieldConfigArgument{
-					"name":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-					"email":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-					"password": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"name":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"email":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"password":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"inviteCode": &graphql.ArgumentConfig{Type: graphql.String}, // Obrigatório no modo invite
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					name := p.Args["name"].(string)
 					email := p.Args["email"].(string)
 					password := p.Args["password"].(string)
+					inviteCode, _ := p.Args["inviteCode"].(string)
 
-					// Cadastra novo usuário
-					return resolver.Store.Signup(name, email, password)
+					// Cadastra novo usuário e envia o link de confirmação
+					return resolver.Signup(p.Context, name, email, password, inviteCode)
+				},
+			},
+			"verifyEmail": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"token": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.VerifyEmail(p.Context, p.Args["token"].(string))
+				},
+			},
+			"resendVerificationEmail": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"email": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.ResendVerificationEmail(p.Context, p.Args["email"].(string))
 				},
 			},
 			"login": &graphql.Field{
@@ -140,35 +345,302 @@ func NewSchema(resolver *Resolver) (graphql.Schema, error) {
 				Args: graphql.FieldConfigArgument{
 					"email":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 					"password": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"cookie":   &graphql.ArgumentConfig{Type: graphql.Boolean}, // Tokens em cookies HttpOnly (front end web)
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					email := p.Args["email"].(string)
 					password := p.Args["password"].(string)
+					cookie, _ := p.Args["cookie"].(bool)
 
-					// Valida credenciais
-					if !resolver.Store.Authenticate(email, password) {
-						return nil, errors.New("credenciais inválidas")
+					// Valida credenciais e gera os tokens da nova sessão
+					return resolver.Login(p.Context, email, password, cookie)
+				},
+			},
+			"revokeSession": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.RevokeSession(p.Context, p.Args["id"].(string))
+				},
+			},
+			"verifyTwoFactor": &graphql.Field{
+				Type: loginResponseType,
+				Args: graphql.FieldConfigArgument{
+					"challengeToken": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"code":           &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}, // TOTP ou código de recuperação
+					"cookie":         &graphql.ArgumentConfig{Type: graphql.Boolean},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cookie, _ := p.Args["cookie"].(bool)
+					return resolver.VerifyTwoFactor(p.Context, p.Args["challengeToken"].(string), p.Args["code"].(string), cookie)
+				},
+			},
+			"enableTwoFactor": &graphql.Field{
+				Type: twoFactorSetupType,
+				Args: graphql.FieldConfigArgument{
+					"password": &graphql.ArgumentConfig{Type: graphql.String}, // Opcional para contas sem senha
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					password, _ := p.Args["password"].(string)
+					return resolver.EnableTwoFactor(p.Context, password)
+				},
+			},
+			"confirmTwoFactor": &graphql.Field{
+				Type: graphql.NewList(graphql.String), // Códigos de recuperação
+				Args: graphql.FieldConfigArgument{
+					"code": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.ConfirmTwoFactor(p.Context, p.Args["code"].(string))
+				},
+			},
+			"disableTwoFactor": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"password": &graphql.ArgumentConfig{Type: graphql.String},
+					"code":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					password, _ := p.Args["password"].(string)
+					return resolver.DisableTwoFactor(p.Context, password, p.Args["code"].(string))
+				},
+			},
+			"refreshToken": &graphql.Field{
+				Type: loginResponseType,
+				Args: graphql.FieldConfigArgument{
+					"refreshToken": &graphql.ArgumentConfig{Type: graphql.String}, // Omitido no modo cookie
+					"cookie":       &graphql.ArgumentConfig{Type: graphql.Boolean},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					refreshToken, _ := p.Args["refreshToken"].(string)
+					cookie, _ := p.Args["cookie"].(bool)
+					return resolver.RefreshToken(p.Context, refreshToken, cookie)
+				},
+			},
+			// Encerra apenas a sessão atual
+			"logout": &graphql.Field{
+				Type: graphql.Boolean,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.Logout(p.Context)
+				},
+			},
+			// Encerra todas as sessões do usuário (todos os dispositivos)
+			"logoutAll": &graphql.Field{
+				Type: graphql.Boolean,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.LogoutAll(p.Context)
+				},
+			},
+			// Envia por email um link de uso único para redefinir a senha
+			"requestPasswordReset": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"email": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.RequestPasswordReset(p.Context, p.Args["email"].(string))
+				},
+			},
+			"resetPassword": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"token":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"newPassword": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					token := p.Args["token"].(string)
+					newPassword := p.Args["newPassword"].(string)
+					return resolver.ResetPassword(p.Context, token, newPassword)
+				},
+			},
+			// Desbloqueia a conta pelo link enviado quando ela é bloqueada
+			"unlockAccount": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"token": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.UnlockAccount(p.Context, p.Args["token"].(string))
+				},
+			},
+			// Autoatendimento da conta do usuário autenticado
+			"updateProfile": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.UpdateProfile(p.Context, p.Args["name"].(string))
+				},
+			},
+			"changePassword": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"currentPassword": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"newPassword":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					current := p.Args["currentPassword"].(string)
+					newPassword := p.Args["newPassword"].(string)
+					return resolver.ChangePassword(p.Context, current, newPassword)
+				},
+			},
+			// O novo email só vale depois de confirmado via verifyEmail
+			"changeEmail": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"newEmail": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"password": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					newEmail := p.Args["newEmail"].(string)
+					password := p.Args["password"].(string)
+					return resolver.ChangeEmail(p.Context, newEmail, password)
+				},
+			},
+			"deleteAccount": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"password": &graphql.ArgumentConfig{Type: graphql.String}, // Opcional só para contas sem senha
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					password, _ := p.Args["password"].(string)
+					return resolver.DeleteAccount(p.Context, password)
+				},
+			},
+			// Chaves de API para acesso de máquinas (scopes: read, write, admin)
+			"createApiKey": &graphql.Field{
+				Type: createAPIKeyResponseType,
+				Args: graphql.FieldConfigArgument{
+					"name":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"scopes":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.String))},
+					"expiresAt": &graphql.ArgumentConfig{Type: graphql.String}, // RFC 3339; vazio = não expira
+					"userId":    &graphql.ArgumentConfig{Type: graphql.String}, // Somente admin
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var scopes []string
+					for _, sc := range p.Args["scopes"].([]interface{}) {
+						scopes = append(scopes, sc.(string))
 					}
-
-					// Gera token JWT
-					token, err := auth.GenerateToken(email)
-					if err != nil {
-						return nil, err
+					expiresAt, _ := p.Args["expiresAt"].(string)
+					userID, _ := p.Args["userId"].(string)
+					return resolver.CreateAPIKey(p.Context, p.Args["name"].(string), scopes, expiresAt, userID)
+				},
+			},
+			"revokeApiKey": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.RevokeAPIKey(p.Context, p.Args["id"].(string))
+				},
+			},
+			// Convites de cadastro (somente admin, ver accessRules)
+			"createInvite": &graphql.Field{
+				Type: createInviteResponseType,
+				Args: graphql.FieldConfigArgument{
+					"maxUses":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+					"expiresAt": &graphql.ArgumentConfig{Type: graphql.String}, // RFC 3339; vazio = não expira
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					expiresAt, _ := p.Args["expiresAt"].(string)
+					return resolver.CreateInvite(p.Context, p.Args["maxUses"].(int), expiresAt)
+				},
+			},
+			"revokeInvite": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.RevokeInvite(p.Context, p.Args["id"].(string))
+				},
+			},
+			// Administração do cache de filmes (somente admin, ver accessRules)
+			"invalidateCache": &graphql.Field{
+				Type: graphql.Int, // Quantidade de filmes removidos
+				Args: graphql.FieldConfigArgument{
+					"id":     &graphql.ArgumentConfig{Type: graphql.String},
+					"prefix": &graphql.ArgumentConfig{Type: graphql.String},
+					"all":    &graphql.ArgumentConfig{Type: graphql.Boolean},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _ := p.Args["id"].(string)
+					prefix, _ := p.Args["prefix"].(string)
+					all, _ := p.Args["all"].(bool)
+					return resolver.InvalidateCache(p.Context, id, prefix, all)
+				},
+			},
+			"refreshMovie": &graphql.Field{
+				Type: movieType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.RefreshMovie(p.Context, p.Args["id"].(string))
+				},
+			},
+			// Administração de usuários (somente admin, ver accessRules)
+			"setUserRoles": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"userId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"roles":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.String))},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var roles []string
+					for _, r := range p.Args["roles"].([]interface{}) {
+						roles = append(roles, r.(string))
 					}
-
-					// Retorna o token e email
-					return map[string]interface{}{
-						"email": email,
-						"token": token,
-					}, nil
+					return resolver.Store.SetRoles(p.Args["userId"].(string), roles)
+				},
+			},
+			"unlockUser": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"email": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					resolver.Store.UnlockUser(p.Args["email"].(string))
+					return true, nil
 				},
 			},
 		},
 	})
 
+	// Aplica as regras de acesso por papel em um único lugar
+	if err := enforceAccess(accessRules, sessionOnlyFields, queryType, mutationType); err != nil {
+		return graphql.Schema{}, err
+	}
+
 	// Cria o schema GraphQL completo (query + mutation)
 	return graphql.NewSchema(graphql.SchemaConfig{
 		Query:    queryType,
 		Mutation: mutationType,
 	})
 }
+
+// Resolver de campo que lê um valor de *model.APIKey
+func apiKeyField(get func(*model.APIKey) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		k, ok := p.Source.(*model.APIKey)
+		if !ok {
+			return nil, nil
+		}
+		return get(k), nil
+	}
+}
+
+// Resolver de campo que lê um valor de *model.Invite
+func inviteField(get func(*model.Invite) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		inv, ok := p.Source.(*model.Invite)
+		if !ok {
+			return nil, nil
+		}
+		return get(inv), nil
+	}
+}
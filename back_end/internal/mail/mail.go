@@ -0,0 +1,15 @@
+package mail
+
+import "context"
+
+// Mensagem de email em texto simples
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer entrega mensagens; implementações: SMTP (produção) e Outbox (dev/testes)
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// OutboxMailer grava cada email como um arquivo .eml em uma pasta,
+// permitindo desenvolver e testar sem servidor de email
+type OutboxMailer struct {
+	mu   sync.Mutex
+	Dir  string
+	From string
+	seq  int // Desempata emails gravados no mesmo instante
+}
+
+// Cria um OutboxMailer que grava na pasta informada
+func NewOutboxMailer(dir, from string) *OutboxMailer {
+	return &OutboxMailer{Dir: dir, From: from}
+}
+
+// Grava a mensagem em <Dir>/<data>-<seq>.eml
+func (m *OutboxMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return err
+	}
+
+	m.seq++
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().Format("20060102T150405.000000000"), m.seq)
+	return os.WriteFile(filepath.Join(m.Dir, name), compose(m.From, msg), 0o600)
+}
@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer envia emails por um servidor SMTP (usa STARTTLS quando disponível)
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string // Vazio desativa a autenticação
+	Password string
+	From     string // Remetente, ex: "CineBase <nao-responda@cinebase.app>"
+
+	sender string // Só o endereço de From, usado no MAIL FROM do envelope
+}
+
+// Cria um SMTPMailer com as configurações informadas; from inválido é erro
+func NewSMTPMailer(host, port, username, password, from string) (*SMTPMailer, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("remetente de email inválido %q: %w", from, err)
+	}
+	return &SMTPMailer{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+		sender:   addr.Address,
+	}, nil
+}
+
+// Envia a mensagem; o context não interrompe uma conversa SMTP já iniciada
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// O envelope leva só o endereço; o nome de exibição fica no cabeçalho From
+	addr := net.JoinHostPort(m.Host, m.Port)
+	if err := smtp.SendMail(addr, auth, m.sender, []string{msg.To}, compose(m.From, msg)); err != nil {
+		return fmt.Errorf("erro ao enviar email: %w", err)
+	}
+	return nil
+}
+
+// Monta a mensagem no formato RFC 5322: cabeçalhos + corpo em UTF-8 com CRLF
+func compose(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
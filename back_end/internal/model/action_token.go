@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// Token de uso único enviado por email (redefinição de senha, verificação...)
+type ActionToken struct {
+	Hash      string    `json:"-"` // SHA-256 do token; o valor original só existe no email
+	UserID    string    `json:"user_id"`
+	Purpose   string    `json:"purpose"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Data      string    `json:"data,omitempty"` // Dado extra da ação (ex: novo email)
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Escopos de chave de API
+const (
+	ScopeRead  = "read"  // Queries
+	ScopeWrite = "write" // Mutations
+	ScopeAdmin = "admin" // Campos de admin (só vale se o dono da chave for admin)
+)
+
+// Chave de API para acesso máquina-a-máquina em nome de um usuário
+type APIKey struct {
+	ID         string     `json:"id"` // Parte pública da chave, usada na busca
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"-"` // SHA-256 da parte secreta
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"` // nil = não expira
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Indica se a chave tem o escopo
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Indica se a chave ainda pode ser usada
+func (k *APIKey) Active(now time.Time) bool {
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
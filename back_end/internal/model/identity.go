@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// Vínculo entre um usuário e uma conta em um provedor externo (OpenID Connect)
+type Identity struct {
+	Issuer   string    `json:"issuer"`  // Provedor que emitiu o "sub"
+	Subject  string    `json:"subject"` // ID do usuário no provedor
+	UserID   string    `json:"user_id"`
+	Email    string    `json:"email"` // Email informado pelo provedor no vínculo
+	LinkedAt time.Time `json:"linked_at"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Convite de cadastro criado por um admin; o código só existe como hash
+type Invite struct {
+	ID        string     `json:"id"`
+	Hash      string     `json:"-"` // SHA-256 do código
+	CreatedBy string     `json:"created_by"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"` // nil = não expira
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// Indica se o convite ainda aceita cadastros
+func (i *Invite) Active(now time.Time) bool {
+	return i.RevokedAt == nil && i.Uses < i.MaxUses && (i.ExpiresAt == nil || now.Before(*i.ExpiresAt))
+}
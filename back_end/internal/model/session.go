@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Sessão de login; cada sessão tem um refresh token que é trocado a cada uso
+type Session struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	RefreshHash string     `json:"-"` // hash do refresh token atual
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+
+	// Dispositivo que abriu a sessão, atualizados a cada uso
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Indica se a sessão ainda pode ser usada
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -1,8 +1,32 @@
 package model
 
+// Papéis de usuário; admin tem acesso a tudo que user tem
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"-"` // senha em hash
+
+	EmailVerified bool     `json:"email_verified"` // true depois de confirmar o link enviado no cadastro
+	Roles         []string `json:"roles"`
+
+	TwoFactorEnabled bool     `json:"two_factor_enabled"` // true depois de confirmar o primeiro código TOTP
+	TOTPSecret       string   `json:"-"`                  // Segredo base32 (pendente enquanto TwoFactorEnabled for false)
+	TOTPLastStep     int64    `json:"-"`                  // Último passo de 30s aceito, para impedir reuso do código
+	RecoveryCodes    []string `json:"-"`                  // Hashes dos códigos de recuperação ainda não usados
+}
+
+// Indica se o usuário tem o papel (admin tem todos)
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,89 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Gera um valor aleatório para state, nonce e code_verifier
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// code_challenge do PKCE (método S256)
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Monta a URL de autorização para onde o navegador é redirecionado
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.meta.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// Troca o código de autorização pelo ID token (com o code_verifier do PKCE)
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("erro na troca do código: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("resposta inválida do token endpoint: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("token endpoint recusou o código: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("token endpoint não retornou id_token")
+	}
+	return body.IDToken, nil
+}
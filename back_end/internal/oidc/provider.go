@@ -0,0 +1,256 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Configuração de um provedor OpenID Connect
+type ProviderConfig struct {
+	Name         string // Nome usado nas rotas, ex: "google"
+	IssuerURL    string // Ex: https://accounts.google.com
+	ClientID     string
+	ClientSecret string   // Opcional para clientes públicos (só PKCE)
+	RedirectURL  string   // Callback registrado no provedor
+	Scopes       []string // Padrão: openid email profile
+}
+
+// Endpoints publicados em /.well-known/openid-configuration
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider guarda a configuração, os endpoints descobertos e as chaves públicas do provedor
+type Provider struct {
+	cfg    ProviderConfig
+	meta   discovery
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]interface{} // Chaves públicas por "kid"
+	fetchedAt time.Time
+}
+
+// Claims do ID token que usamos
+type IDClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"` // Alguns provedores mandam "true" como string
+	Name          string      `json:"name"`
+}
+
+// Indica se o provedor garante que o email pertence ao usuário
+func (c *IDClaims) Verified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// Busca a configuração do provedor (discovery) e monta o Provider
+func NewProvider(ctx context.Context, cfg ProviderConfig, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	url := strings.TrimSuffix(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	var meta discovery
+	if err := getJSON(ctx, client, url, &meta); err != nil {
+		return nil, fmt.Errorf("discovery de %s: %w", cfg.Name, err)
+	}
+
+	// O issuer publicado precisa ser o configurado (OIDC Discovery, seção 4.3)
+	if strings.TrimSuffix(meta.Issuer, "/") != strings.TrimSuffix(cfg.IssuerURL, "/") {
+		return nil, fmt.Errorf("issuer de %s não confere: %q", cfg.Name, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery de %s incompleto", cfg.Name)
+	}
+
+	return &Provider{cfg: cfg, meta: meta, client: client}, nil
+}
+
+// Nome do provedor
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// Issuer do provedor (identifica de onde vem o "sub" do usuário)
+func (p *Provider) Issuer() string {
+	return p.meta.Issuer
+}
+
+// Valida assinatura, issuer, audience, expiração e nonce do ID token
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDClaims, error) {
+	claims := &IDClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(p.meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("id_token inválido: %w", err)
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("id_token com nonce inesperado")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("id_token sem subject")
+	}
+	return claims, nil
+}
+
+// Retorna a chave pelo "kid"; kid desconhecido força uma nova leitura do JWKS
+// (no máximo uma vez por minuto), cobrindo a rotação de chaves do provedor
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	if time.Since(p.fetchedAt) < time.Minute {
+		return nil, fmt.Errorf("kid %q desconhecido", kid)
+	}
+
+	keys, err := p.fetchKeys(ctx)
+	p.fetchedAt = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("kid %q desconhecido", kid)
+}
+
+// Formato de uma chave no JWKS
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Baixa e converte as chaves do JWKS do provedor
+func (p *Provider) fetchKeys(ctx context.Context) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, p.client, p.meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("jwks de %s: %w", p.cfg.Name, err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue // Ignora tipos que não usamos em vez de recusar o conjunto todo
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+// Converte o JWK para a chave pública correspondente
+func (k jwk) publicKey() (interface{}, error) {
+	b64 := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := b64(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curva %q não suportada", k.Crv)
+		}
+		x, err := b64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("curva %q não suportada", k.Crv)
+		}
+		x, err := b64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("tipo de chave %q não suportado", k.Kty)
+}
+
+// Faz um GET e decodifica a resposta JSON
+func getJSON(ctx context.Context, client *http.Client, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,202 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"movies-api/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Tempo que o usuário tem para concluir o login no provedor
+const pendingTTL = 10 * time.Minute
+
+// Cookie que liga o state ao navegador que começou o login: sem ele, um callback
+// montado por outra pessoa (login CSRF) colocaria a vítima na conta do atacante
+const stateCookie = "oidc_state"
+
+// Login iniciado e ainda não concluído, indexado pelo "state"
+type pending struct {
+	provider  string
+	nonce     string
+	verifier  string
+	createdAt time.Time
+}
+
+// Service expõe as rotas de login social e liga as identidades externas aos usuários
+type Service struct {
+	store     *auth.Store
+	providers map[string]*Provider
+	appURL    string // Front end que recebe os tokens ao final
+
+	mu      sync.Mutex
+	pending map[string]pending
+}
+
+// Cria o serviço com os provedores já descobertos
+func NewService(store *auth.Store, appURL string, providers ...*Provider) *Service {
+	s := &Service{
+		store:     store,
+		providers: make(map[string]*Provider),
+		appURL:    strings.TrimSuffix(appURL, "/"),
+		pending:   make(map[string]pending),
+	}
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+	}
+	return s
+}
+
+// Registra GET /auth/oidc/:provider/login e GET /auth/oidc/:provider/callback
+func (s *Service) Register(router fiber.Router) {
+	router.Get("/auth/oidc/:provider/login", s.login)
+	router.Get("/auth/oidc/:provider/callback", s.callback)
+}
+
+// Redireciona o navegador para o provedor com state, nonce e PKCE
+func (s *Service) login(c *fiber.Ctx) error {
+	p, ok := s.providers[c.Params("provider")]
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "provedor desconhecido"})
+	}
+
+	state, err1 := randomString()
+	nonce, err2 := randomString()
+	verifier, err3 := randomString()
+	if err1 != nil || err2 != nil || err3 != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "erro ao iniciar login"})
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	for k, v := range s.pending {
+		if now.Sub(v.createdAt) > pendingTTL {
+			delete(s.pending, k)
+		}
+	}
+	s.pending[state] = pending{provider: p.Name(), nonce: nonce, verifier: verifier, createdAt: now}
+	s.mu.Unlock()
+
+	setStateCookie(c, p.Name(), hashState(state), now.Add(pendingTTL))
+	return c.Redirect(p.AuthCodeURL(state, nonce, verifier), fiber.StatusFound)
+}
+
+// Recebe o código do provedor, valida o ID token, vincula/cria o usuário
+// e devolve nossos próprios tokens ao front end no fragmento da URL
+func (s *Service) callback(c *fiber.Ctx) error {
+	state := c.Query("state")
+
+	// O state precisa ter começado neste navegador; só então o login pendente é consumido
+	bound := subtle.ConstantTimeCompare([]byte(c.Cookies(stateCookie)), []byte(hashState(state))) == 1
+	setStateCookie(c, c.Params("provider"), "", time.Unix(0, 0))
+	if state == "" || !bound {
+		return s.fail(c, "login expirado, tente novamente")
+	}
+
+	// O state é de uso único
+	s.mu.Lock()
+	pend, ok := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+
+	if !ok || pend.provider != c.Params("provider") || time.Since(pend.createdAt) > pendingTTL {
+		return s.fail(c, "login expirado, tente novamente")
+	}
+	if e := c.Query("error"); e != "" {
+		return s.fail(c, "login cancelado no provedor")
+	}
+
+	p := s.providers[pend.provider]
+	ctx := c.UserContext()
+
+	rawIDToken, err := p.Exchange(ctx, c.Query("code"), pend.verifier)
+	if err != nil {
+		log.Printf("OIDC %s: %v", p.Name(), err)
+		return s.fail(c, "não foi possível concluir o login")
+	}
+	claims, err := p.VerifyIDToken(ctx, rawIDToken, pend.nonce)
+	if err != nil {
+		log.Printf("OIDC %s: %v", p.Name(), err)
+		return s.fail(c, "não foi possível concluir o login")
+	}
+
+	user, err := s.store.LoginWithIdentity(auth.ExternalIdentity{
+		Issuer:        p.Issuer(),
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.Verified(),
+		Name:          claims.Name,
+	})
+	if err != nil {
+		return s.fail(c, err.Error())
+	}
+
+	// Com dois fatores ativos o provedor só substitui a senha: o front end recebe
+	// o mesmo desafio do login por senha e o conclui em verifyTwoFactor
+	if user.TwoFactorEnabled {
+		challenge, err := s.store.StartTwoFactorChallenge(user)
+		if err != nil {
+			return s.fail(c, "não foi possível concluir o login")
+		}
+		frag := url.Values{}
+		frag.Set("twoFactorRequired", "1")
+		frag.Set("challengeToken", challenge)
+		frag.Set("email", user.Email)
+		return c.Redirect(s.appURL+"/login/oidc#"+frag.Encode(), fiber.StatusFound)
+	}
+
+	// A sessão registra o dispositivo que concluiu o login
+	pair, err := s.store.StartSession(auth.WithClient(ctx, auth.ClientFromRequest(c)), user)
+	if err != nil {
+		return s.fail(c, "não foi possível concluir o login")
+	}
+
+	// Fragmento (#) não é enviado a servidores, então os tokens não vão parar em logs.
+	// No modo cookie os tokens vão em cookies HttpOnly e não aparecem na URL.
+	frag := url.Values{}
+	if s.store.CookiesEnabled() {
+		if err := s.store.WriteSessionCookies(c, pair); err != nil {
+			return s.fail(c, "não foi possível concluir o login")
+		}
+		frag.Set("cookie", "1")
+	} else {
+		frag.Set("token", pair.AccessToken)
+		frag.Set("refreshToken", pair.RefreshToken)
+	}
+	frag.Set("expiresAt", pair.ExpiresAt.Format(time.RFC3339))
+	frag.Set("email", user.Email)
+	return c.Redirect(s.appURL+"/login/oidc#"+frag.Encode(), fiber.StatusFound)
+}
+
+// Grava (ou, com expires no passado, apaga) o cookie do state, visível só nas rotas
+// do provedor. Lax: ele volta no redirecionamento do provedor, uma navegação GET
+// vinda de outro site.
+func setStateCookie(c *fiber.Ctx, provider, value string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     stateCookie,
+		Value:    value,
+		Path:     "/auth/oidc/" + provider,
+		Expires:  expires,
+		Secure:   c.Secure(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// Hash do state guardado no cookie
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// Volta ao front end com a mensagem de erro
+func (s *Service) fail(c *fiber.Ctx, msg string) error {
+	return c.Redirect(s.appURL+"/login?erro="+url.QueryEscape(msg), fiber.StatusFound)
+}
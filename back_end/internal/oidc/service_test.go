@@ -0,0 +1,345 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"movies-api/internal/auth"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "cinebase-test"
+	testRedirectURL = "http://api.test/auth/oidc/test/callback"
+	testAppURL      = "http://app.test"
+)
+
+// Código emitido pelo provedor de teste, com o que veio na autorização
+type issuedCode struct {
+	nonce     string
+	challenge string
+}
+
+// Provedor OpenID em processo: discovery, JWKS, autorização e token endpoint
+type testIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu          sync.Mutex
+	codes       map[string]issuedCode
+	badVerifier bool // Guarda o challenge de outro verifier, para o PKCE não conferir
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{t: t, key: key, codes: make(map[string]issuedCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", iss.discovery)
+	mux.HandleFunc("/jwks", iss.jwks)
+	mux.HandleFunc("/authorize", iss.authorize)
+	mux.HandleFunc("/token", iss.token)
+	iss.server = httptest.NewServer(mux)
+	t.Cleanup(iss.server.Close)
+	return iss
+}
+
+func (iss *testIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	base := iss.server.URL
+	json.NewEncoder(w).Encode(discovery{
+		Issuer:                base,
+		AuthorizationEndpoint: base + "/authorize",
+		TokenEndpoint:         base + "/token",
+		JWKSURI:               base + "/jwks",
+	})
+}
+
+func (iss *testIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	pub := iss.key.PublicKey
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []jwk{{
+			Kty: "RSA",
+			Kid: "k1",
+			Use: "sig",
+			N:   b64(pub.N.Bytes()),
+			E:   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// Aprova o login na hora e volta ao redirect_uri com o código
+func (iss *testIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != testClientID || q.Get("redirect_uri") != testRedirectURL ||
+		q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		iss.t.Errorf("autorização inesperada: %s", r.URL.RawQuery)
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code, _ := randomString()
+	iss.mu.Lock()
+	issued := issuedCode{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	if iss.badVerifier {
+		issued.challenge = codeChallenge("outro-verifier")
+	}
+	iss.codes[code] = issued
+	iss.mu.Unlock()
+
+	back := url.Values{}
+	back.Set("code", code)
+	back.Set("state", q.Get("state"))
+	http.Redirect(w, r, q.Get("redirect_uri")+"?"+back.Encode(), http.StatusFound)
+}
+
+// Troca o código pelo ID token conferindo o code_verifier do PKCE
+func (iss *testIssuer) token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fail := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		fail("invalid_request")
+		return
+	}
+	iss.mu.Lock()
+	issued, ok := iss.codes[r.PostForm.Get("code")]
+	delete(iss.codes, r.PostForm.Get("code"))
+	iss.mu.Unlock()
+	if !ok || r.PostForm.Get("client_id") != testClientID || r.PostForm.Get("redirect_uri") != testRedirectURL {
+		fail("invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != issued.challenge {
+		fail("invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, IDClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    iss.server.URL,
+			Subject:   "user-123",
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+		Nonce:         issued.nonce,
+		Email:         "Social@Example.com",
+		EmailVerified: true,
+		Name:          "Social",
+	})
+	tok.Header["kid"] = "k1"
+	signed, err := tok.SignedString(iss.key)
+	if err != nil {
+		iss.t.Error(err)
+		fail("server_error")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+}
+
+// Monta o app com o serviço registrado e um Store em memória
+func newTestApp(t *testing.T, iss *testIssuer) (*fiber.App, *auth.Store) {
+	t.Helper()
+	key, err := auth.NewHMACKey("test", []byte("segredo-de-teste-com-32-bytes!!!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := auth.NewKeySet("test", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.SetKeySet(ks)
+
+	store, err := auth.NewStore(auth.NewMemoryBackend(), auth.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewProvider(context.Background(), ProviderConfig{
+		Name:        "test",
+		IssuerURL:   iss.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	}, iss.server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	NewService(store, testAppURL, provider).Register(app)
+	return app, store
+}
+
+// Faz a requisição no app com os cookies informados e retorna o destino do
+// redirecionamento e os cookies gravados na resposta
+func redirectFrom(t *testing.T, app *fiber.App, target string, cookies ...*http.Cookie) (*url.URL, []*http.Cookie) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("GET %s: status %d, esperado 302", target, resp.StatusCode)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc, resp.Cookies()
+}
+
+// Cookie do state gravado pelo login
+func stateCookieFrom(t *testing.T, cookies []*http.Cookie) *http.Cookie {
+	t.Helper()
+	for _, cookie := range cookies {
+		if cookie.Name == stateCookie {
+			if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/auth/oidc/test" {
+				t.Errorf("cookie do state sem HttpOnly, Lax ou path do provedor: %+v", cookie)
+			}
+			return cookie
+		}
+	}
+	t.Fatal("login não gravou o cookie do state")
+	return nil
+}
+
+// Inicia o login no app e passa pelo provedor; retorna a URL do callback e o cookie do state
+func authorize(t *testing.T, app *fiber.App, iss *testIssuer) (callback *url.URL, cookie *http.Cookie) {
+	t.Helper()
+	authorizeURL, cookies := redirectFrom(t, app, "/auth/oidc/test/login")
+	if !strings.HasPrefix(authorizeURL.String(), iss.server.URL+"/authorize?") {
+		t.Fatalf("login redirecionou para %s", authorizeURL)
+	}
+	cookie = stateCookieFrom(t, cookies)
+
+	// Cópia do cliente do provedor que não segue o redirecionamento de volta ao callback
+	client := *iss.server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(authorizeURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err = url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback, cookie
+}
+
+// Percorre login → provedor → callback no mesmo navegador e retorna a URL final no
+// front end, a do callback e o cookie do state
+func runFlow(t *testing.T, app *fiber.App, iss *testIssuer) (final, callback *url.URL, cookie *http.Cookie) {
+	t.Helper()
+	callback, cookie = authorize(t, app, iss)
+	final, _ = redirectFrom(t, app, callback.RequestURI(), cookie)
+	return final, callback, cookie
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	iss := newTestIssuer(t)
+	app, store := newTestApp(t, iss)
+
+	final, callback, cookie := runFlow(t, app, iss)
+	if final.Path != "/login/oidc" {
+		t.Fatalf("callback redirecionou para %s", final)
+	}
+	frag, err := url.ParseQuery(final.Fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frag.Get("token") == "" || frag.Get("refreshToken") == "" {
+		t.Fatalf("tokens ausentes no fragmento: %s", final.Fragment)
+	}
+	if frag.Get("email") != "social@example.com" {
+		t.Errorf("email = %q", frag.Get("email"))
+	}
+	if _, err := auth.ParseToken(frag.Get("token")); err != nil {
+		t.Errorf("access token inválido: %v", err)
+	}
+
+	user, err := store.GetByEmail("social@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.EmailVerified {
+		t.Error("conta criada pelo provedor deveria estar com email verificado")
+	}
+
+	// O state é de uso único: repetir o callback, mesmo com o cookie, volta com erro
+	again, _ := redirectFrom(t, app, callback.RequestURI(), cookie)
+	if again.Path != "/login" || again.Query().Get("erro") == "" {
+		t.Errorf("callback repetido redirecionou para %s", again)
+	}
+}
+
+func TestCallbackRejectsWrongVerifier(t *testing.T) {
+	iss := newTestIssuer(t)
+	app, _ := newTestApp(t, iss)
+
+	iss.mu.Lock()
+	iss.badVerifier = true
+	iss.mu.Unlock()
+
+	final, _, _ := runFlow(t, app, iss)
+	if final.Path != "/login" || final.Query().Get("erro") == "" {
+		t.Fatalf("login com PKCE inválido redirecionou para %s", final)
+	}
+}
+
+func TestCallbackRequiresStateCookie(t *testing.T) {
+	iss := newTestIssuer(t)
+	app, store := newTestApp(t, iss)
+
+	// Callback levado a outro navegador (login CSRF): sem o cookie, ou com o cookie
+	// de outro login, é recusado
+	callback, cookie := authorize(t, app, iss)
+	_, otherCookie := authorize(t, app, iss)
+
+	for name, cookies := range map[string][]*http.Cookie{
+		"sem cookie":            nil,
+		"cookie de outro login": {otherCookie},
+	} {
+		final, _ := redirectFrom(t, app, callback.RequestURI(), cookies...)
+		if final.Path != "/login" || final.Query().Get("erro") == "" {
+			t.Errorf("callback %s redirecionou para %s", name, final)
+		}
+	}
+	if _, err := store.GetByEmail("social@example.com"); err == nil {
+		t.Error("callback recusado não deveria criar a conta")
+	}
+
+	// A recusa não consome o login pendente: o navegador que o iniciou ainda conclui
+	final, _ := redirectFrom(t, app, callback.RequestURI(), cookie)
+	if final.Path != "/login/oidc" {
+		t.Fatalf("callback com o cookie certo redirecionou para %s", final)
+	}
+}
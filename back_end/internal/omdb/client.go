@@ -2,6 +2,7 @@ package omdb
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -21,6 +22,16 @@ type rawMovie struct {
 	Released   string `json:"Released"`   // Data de lançamento (string)
 }
 
+// ErrNotFound indica que a OMDb não conhece o ID: repetir a requisição não adianta,
+// ao contrário de falhas de rede, de cota ou de chave
+var ErrNotFound = errors.New("filme não encontrado na OMDb")
+
+// Mensagens da OMDb para IDs inexistentes ou mal formados
+var notFoundMessages = map[string]bool{
+	"Movie not found!":   true,
+	"Incorrect IMDb ID.": true,
+}
+
 // Cliente OMDb contendo chave da API e cliente HTTP configurado
 type Client struct {
 	APIKey     string       // Chave de acesso à API OMDb
@@ -62,6 +73,9 @@ func (c *Client) FetchMovieByID(id string) (*rawMovie, error) {
 
 	// Verifica se a resposta da OMDb foi "True"
 	if data.Response != "True" {
+		if notFoundMessages[data.Error] {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, data.Error)
+		}
 		return nil, fmt.Errorf("OMDb erro: %s", data.Error)
 	}
 